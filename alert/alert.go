@@ -1,9 +1,18 @@
 package alert
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 // Alerter is an interface for implementing alerts on various channels
 type Alerter interface {
 	// PostAlert posts the alert with the given message.
 	PostAlert(ctx context.Context, matchedKeywords []string)
 }
+
+// HttpClientSetter is an interface for alerters which issue http requests and accept a shared http client.
+type HttpClientSetter interface {
+	// SetHttpClient sets the http client used when executing requests.
+	SetHttpClient(client *http.Client)
+}
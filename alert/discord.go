@@ -53,6 +53,11 @@ func NewDiscordWebhookAlerter(options DiscordWebhookAlerterOptions) (*DiscordWeb
 	}, nil
 }
 
+// SetHttpClient sets the http client used when executing requests.
+func (d *DiscordWebhookAlerter) SetHttpClient(client *http.Client) {
+	d.HttpClient = client
+}
+
 // PostAlert posts the alert on Discord via webhooks.
 func (d *DiscordWebhookAlerter) PostAlert(ctx context.Context, matchedKeywords []string) {
 	alertMessage := strings.Replace(d.messageTemplate, "$keywords", strings.Join(matchedKeywords, ","), -1)
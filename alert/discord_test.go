@@ -74,3 +74,16 @@ func Test_DiscordWebhookAlerter_PostAlert(t *testing.T) {
 
 	alerter.PostAlert(context.Background(), []string{"matched", "second"})
 }
+
+func Test_DiscordWebhookAlerter_SetHttpClient(t *testing.T) {
+	alerter, err := NewDiscordWebhookAlerter(DiscordWebhookAlerterOptions{
+		Webhook:         "https://example.com",
+		MessageTemplate: "test $keywords",
+	})
+	assert.NoError(t, err)
+
+	client := &http.Client{}
+	var setter HttpClientSetter = alerter
+	setter.SetHttpClient(client)
+	assert.Same(t, client, alerter.HttpClient)
+}
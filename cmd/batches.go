@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"hotalert/logging"
+	"hotalert/task"
+	"hotalert/task/executor"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// waitForBatches logs the task results of the batches until all their tasks are done and shuts down the executor.
+// On the first shutdown signal it drains the executor, see drainExecutor. It returns the exit code of the command,
+// exitTaskFailed if any task failed.
+func waitForBatches(defaultExecutor batchExecutor, batches []*executor.Batch, signals chan os.Signal) int {
+	var results = make(chan *task.Result)
+	var batchGroup sync.WaitGroup
+	for _, batch := range batches {
+		batchGroup.Add(1)
+		go func(batch *executor.Batch) {
+			defer batchGroup.Done()
+			for result := range batch.Results() {
+				results <- result
+			}
+		}(batch)
+	}
+	go func() {
+		batchGroup.Wait()
+		close(results)
+	}()
+
+	var exitCode = exitSuccess
+	var shutdownSignals = signals
+	for results != nil {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+			} else if result.Error() != nil && result.Error() != executor.ErrExecutorStopped {
+				logging.SugaredLogger.Errorf("Failed to execute task %v got: %s", result.InitialTask, result.Error())
+			}
+		case receivedSignal := <-shutdownSignals:
+			exitCode = signalExitCode(receivedSignal)
+			shutdownSignals = nil
+			drainExecutor(defaultExecutor, signals)
+		}
+	}
+	signal.Stop(signals)
+	defaultExecutor.Shutdown()
+
+	var succeeded, failed int
+	for _, batch := range batches {
+		report := batch.Wait()
+		succeeded += report.Succeeded()
+		failed += len(report.Failed())
+	}
+	if exitCode == exitSuccess && failed > 0 {
+		exitCode = exitTaskFailed
+	}
+	logging.SugaredLogger.Infof("Done, %d tasks succeeded and %d failed or were not executed", succeeded, failed)
+	return exitCode
+}
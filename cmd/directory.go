@@ -3,12 +3,82 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 	"hotalert/logging"
+	"hotalert/task/executor"
+	"hotalert/workload"
+	"os"
+	"path/filepath"
+	"sort"
 )
 
+// The directory command executes the tasks of each yaml file from a directory with a single executor.
+// The executor takes tasks of the same priority in turns from each file, so a large file does not delay the others.
+// The executor sections of the files are ignored, the executor is configured with the flags.
+// On SIGINT or SIGTERM it stops adding tasks and drains the executor, see waitForBatches. It exits with
+// exitTaskFailed if any task failed.
 var directoryCmd = &cobra.Command{
 	Use:   "directory",
 	Short: "execute each yaml file from a directory",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		logging.SugaredLogger.Fatal("not implemented")
+		var directoryName = args[0]
+		fileNames, err := yamlFiles(directoryName)
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Failed to read directory %s exiting!", directoryName)
+			return
+		}
+
+		var workloads = make([]*workload.Workload, 0, len(fileNames))
+		for _, fileName := range fileNames {
+			data, err := os.ReadFile(filepath.Join(directoryName, fileName))
+			if err != nil {
+				logging.SugaredLogger.Errorf("Failed to read file %s, skipping it: %s", fileName, err)
+				continue
+			}
+			currentWorkload, err := workload.FromYamlContent(data)
+			if err != nil {
+				logging.SugaredLogger.Errorf("Failed to parse file %s, skipping it: %s", fileName, err)
+				continue
+			}
+			currentWorkload.SetName(fileName)
+			workloads = append(workloads, currentWorkload)
+		}
+		if len(workloads) == 0 {
+			logging.SugaredLogger.Fatalf("No workload found in directory %s exiting!", directoryName)
+			return
+		}
+
+		defaultExecutor, err := newExecutor(cmd, executor.DefaultOptions())
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Invalid executor options: %s", err)
+			return
+		}
+		var signals = notifyShutdownSignals()
+		defaultExecutor.Start()
+		var batches = make([]*executor.Batch, 0, len(workloads))
+		for _, currentWorkload := range workloads {
+			batches = append(batches, defaultExecutor.SubmitBatch(currentWorkload.GetTasks()))
+		}
+
+		exitCode := waitForBatches(defaultExecutor, batches, signals)
+		if exitCode != exitSuccess {
+			os.Exit(exitCode)
+		}
 	},
 }
+
+// yamlFiles returns the sorted names of the yaml files in the directory.
+func yamlFiles(directoryName string) ([]string, error) {
+	entries, err := os.ReadDir(directoryName)
+	if err != nil {
+		return nil, err
+	}
+	var fileNames = make([]string, 0, len(entries))
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (extension == ".yaml" || extension == ".yml") {
+			fileNames = append(fileNames, entry.Name())
+		}
+	}
+	sort.Strings(fileNames)
+	return fileNames, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"hotalert/task"
+	"hotalert/task/executor"
+	"hotalert/taskqueue"
+	"time"
+)
+
+// The executor flags override the executor section of the workload.
+var (
+	workersFlag         int
+	queueSizeFlag       int
+	fullQueueFlag       string
+	hostConcurrencyFlag int
+	hostDelayFlag       float64
+)
+
+// The isolation flags run each task in a worker process with the process executor.
+var (
+	isolateFlag     bool
+	memoryLimitFlag int64
+	cpuLimitFlag    float64
+)
+
+// The queue flags push the tasks to a queue shared with queue workers, with the distributed executor.
+var (
+	queueFlag     string
+	queueNameFlag string
+)
+
+// batchExecutor is an executor which runs batches of tasks.
+type batchExecutor interface {
+	executor.Executor
+	SubmitBatch(tasks []*task.Task) *executor.Batch
+}
+
+func init() {
+	defaultOptions := executor.DefaultOptions()
+	RootCmd.PersistentFlags().IntVar(&workersFlag, "workers", defaultOptions.Workers, "number of tasks executed concurrently")
+	RootCmd.PersistentFlags().IntVar(&queueSizeFlag, "queue-size", defaultOptions.QueueSize, "capacity of the task and result queues")
+	RootCmd.PersistentFlags().StringVar(&fullQueueFlag, "full-queue", defaultOptions.FullQueue, "behavior when the task queue is full: block, drop or error")
+	RootCmd.PersistentFlags().IntVar(&hostConcurrencyFlag, "host-concurrency", defaultOptions.HostConcurrency, "maximum number of concurrent http requests to a host, 0 means no limit")
+	RootCmd.PersistentFlags().Float64Var(&hostDelayFlag, "host-delay", defaultOptions.HostDelay.Seconds(), "minimum number of seconds between two http requests to a host")
+	RootCmd.PersistentFlags().BoolVar(&isolateFlag, "isolate", false, "execute each task in a separate worker process")
+	RootCmd.PersistentFlags().Int64Var(&memoryLimitFlag, "memory-limit", 0, "maximum resident memory of a worker process in megabytes, 0 means no limit")
+	RootCmd.PersistentFlags().Float64Var(&cpuLimitFlag, "cpu-limit", 0, "maximum number of cpu seconds of a worker process, 0 means no limit")
+	RootCmd.PersistentFlags().StringVar(&queueFlag, "queue", "", "url of the queue shared with the queue workers, such as redis://localhost:6379/0")
+	RootCmd.PersistentFlags().StringVar(&queueNameFlag, "queue-name", executor.DefaultDistributedOptions(nil).Name, "name of the queue, the instances with the same name share their tasks")
+}
+
+// newExecutor returns a new executor built from the workload options, overridden by the flags given on the command line.
+// With the queue flag it returns a distributed executor, otherwise see newLocalExecutor.
+func newExecutor(cmd *cobra.Command, options executor.Options) (batchExecutor, error) {
+	options = overrideOptions(cmd, options)
+	if queueFlag == "" {
+		return newLocalExecutor(options)
+	}
+	queue, err := taskqueue.Open(queueFlag)
+	if err != nil {
+		return nil, err
+	}
+	distributedOptions := executor.DefaultDistributedOptions(queue)
+	distributedOptions.Name = queueNameFlag
+	return executor.NewDistributedExecutor(options, distributedOptions)
+}
+
+// overrideOptions returns the executor options overridden by the flags given on the command line.
+func overrideOptions(cmd *cobra.Command, options executor.Options) executor.Options {
+	if cmd.Flags().Changed("workers") {
+		options.Workers = workersFlag
+	}
+	if cmd.Flags().Changed("queue-size") {
+		options.QueueSize = queueSizeFlag
+	}
+	if cmd.Flags().Changed("full-queue") {
+		options.FullQueue = fullQueueFlag
+	}
+	if cmd.Flags().Changed("host-concurrency") {
+		options.HostConcurrency = hostConcurrencyFlag
+	}
+	if cmd.Flags().Changed("host-delay") {
+		options.HostDelay = time.Duration(hostDelayFlag * float64(time.Second))
+	}
+	return options
+}
+
+// newLocalExecutor returns a new executor which executes the tasks on this machine. With the isolate flag it returns
+// a process executor.
+func newLocalExecutor(options executor.Options) (batchExecutor, error) {
+	if !isolateFlag {
+		return executor.NewDefaultExecutorWithOptions(options)
+	}
+	processOptions := executor.DefaultProcessOptions()
+	processOptions.MemoryLimit = memoryLimitFlag * 1024 * 1024
+	processOptions.CpuLimit = time.Duration(cpuLimitFlag * float64(time.Second))
+	return executor.NewProcessExecutor(options, processOptions)
+}
@@ -6,15 +6,15 @@ import (
 	"hotalert/task/executor"
 	"hotalert/workload"
 	"os"
-	"sync"
 )
 
 // The file command executes a tasks from a single file only.
+// On SIGINT or SIGTERM it stops adding tasks and drains the executor, see waitForBatches. It exits with
+// exitTaskFailed if any task failed.
 var fileCmd = &cobra.Command{
 	Use:   "file",
 	Short: "execute tasks from a single file",
 	Run: func(cmd *cobra.Command, args []string) {
-		var isRunning = true
 		var fileName = args[0]
 		data, err := os.ReadFile(fileName)
 		if err != nil {
@@ -27,36 +27,18 @@ var fileCmd = &cobra.Command{
 			return
 		}
 
-		var waitGroup = sync.WaitGroup{}
-		var defaultExecutor = executor.NewDefaultExecutor()
-		taskResultChan := defaultExecutor.Start()
-		defer defaultExecutor.Shutdown()
-
-		// Function that logs task results and marks the task executed in the waitGroup
-		go func() {
-			for isRunning {
-				select {
-				case result := <-taskResultChan:
-					waitGroup.Done()
-					if result.Error() != nil {
-						logging.SugaredLogger.Errorf("Failed to execute task %v got: %s", result.InitialTask, result.Error())
-					}
-				}
-			}
-		}()
-
-		// Add tasks
-		waitGroup.Add(workload.GetTasksLen())
-		for _, task := range workload.GetTasks() {
-			defaultExecutor.AddTask(task)
+		defaultExecutor, err := newExecutor(cmd, workload.ExecutorOptions())
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Invalid executor options: %s", err)
+			return
 		}
+		var signals = notifyShutdownSignals()
+		defaultExecutor.Start()
+		batch := defaultExecutor.SubmitBatch(workload.GetTasks())
 
-		// Wait for tasks to be executed
-		waitGroup.Wait()
-
-		// Turn off logging goroutine
-		isRunning = false
-
-		logging.SugaredLogger.Infof("Done")
+		exitCode := waitForBatches(defaultExecutor, []*executor.Batch{batch}, signals)
+		if exitCode != exitSuccess {
+			os.Exit(exitCode)
+		}
 	},
 }
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hotalert/task/executor"
+	"strings"
+)
+
+// The functions command lists the available task functions and their options.
+var functionsCmd = &cobra.Command{
+	Use:   "functions",
+	Short: "list the available task functions and their options",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range executor.FunctionNames() {
+			function, _ := executor.LookupFunction(name)
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", name)
+			if description := function.Description(); description != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", description)
+			}
+			if optionNames := function.OptionNames(); len(optionNames) > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "  options: %s\n", strings.Join(optionNames, ", "))
+			}
+		}
+	},
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"hotalert/logging"
+	"hotalert/task/executor"
+	"hotalert/taskqueue"
+	"os"
+	"os/signal"
+)
+
+// The queue-worker command executes the tasks pushed to the queue given by the queue flag by the file and directory
+// commands of other instances. The executor flags, including the isolate flag, configure its executor.
+// On SIGINT or SIGTERM it stops pulling tasks and drains the executor, see drainExecutor. The tasks cancelled at the
+// end of the grace period are left to the other workers.
+var queueWorkerCmd = &cobra.Command{
+	Use:   "queue-worker",
+	Short: "execute the tasks pushed to the queue by other instances",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if queueFlag == "" {
+			logging.SugaredLogger.Fatalf("The queue flag is required, exiting!")
+			return
+		}
+		queue, err := taskqueue.Open(queueFlag)
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Failed to open queue: %s", err)
+			return
+		}
+		defer queue.Close()
+
+		localExecutor, err := newLocalExecutor(overrideOptions(cmd, executor.DefaultOptions()))
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Invalid executor options: %s", err)
+			return
+		}
+		distributedOptions := executor.DefaultDistributedOptions(queue)
+		distributedOptions.Name = queueNameFlag
+		queueWorker, err := executor.NewQueueWorker(localExecutor, distributedOptions)
+		if err != nil {
+			logging.SugaredLogger.Fatalf("Invalid queue options: %s", err)
+			return
+		}
+
+		var signals = notifyShutdownSignals()
+		queueWorker.Start()
+		logging.SugaredLogger.Infof("Executing the tasks of queue %s", queueNameFlag)
+		receivedSignal := <-signals
+		drainExecutor(queueWorker, signals)
+		signal.Stop(signals)
+		queueWorker.Shutdown()
+		// The deferred calls do not run on os.Exit.
+		_ = queue.Close()
+		os.Exit(signalExitCode(receivedSignal))
+	},
+}
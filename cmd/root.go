@@ -13,4 +13,7 @@ in yaml files and the program parses the files, executes the tasks and emits ale
 func init() {
 	RootCmd.AddCommand(fileCmd)
 	RootCmd.AddCommand(directoryCmd)
+	RootCmd.AddCommand(functionsCmd)
+	RootCmd.AddCommand(workerCmd)
+	RootCmd.AddCommand(queueWorkerCmd)
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"hotalert/logging"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// The exit codes of the commands which execute tasks. A command stopped by a signal exits with 128 plus the signal
+// number, such as 130 for SIGINT and 143 for SIGTERM.
+const (
+	exitSuccess    = 0
+	exitTaskFailed = 1
+)
+
+// gracePeriodFlag is the number of seconds the running tasks are given to finish after a shutdown signal.
+var gracePeriodFlag float64
+
+func init() {
+	RootCmd.PersistentFlags().Float64Var(&gracePeriodFlag, "grace-period", 30, "number of seconds the running tasks are given to finish on SIGINT or SIGTERM")
+}
+
+// notifyShutdownSignals returns a channel which receives SIGINT and SIGTERM.
+func notifyShutdownSignals() chan os.Signal {
+	var signals = make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	return signals
+}
+
+// signalExitCode returns the exit code of a command stopped by the signal.
+func signalExitCode(receivedSignal os.Signal) int {
+	if number, ok := receivedSignal.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+	return exitTaskFailed
+}
+
+// drainer is an executor or a queue worker which can be drained.
+type drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// drainExecutor stops the executor from accepting tasks and lets the running tasks finish, so that their alerts are
+// posted and their state is saved. The tasks are cancelled when the grace period elapses or when another signal is
+// received.
+func drainExecutor(defaultExecutor drainer, signals <-chan os.Signal) {
+	gracePeriod := time.Duration(gracePeriodFlag * float64(time.Second))
+	logging.SugaredLogger.Infof("Shutting down, waiting up to %s for the running tasks, repeat the signal to stop at once", gracePeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := defaultExecutor.Drain(ctx); err != nil {
+		logging.SugaredLogger.Warnf("Cancelled the running tasks: %s", err)
+	}
+}
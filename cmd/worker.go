@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"hotalert/logging"
+	"hotalert/task/executor"
+	"os"
+)
+
+// The worker command executes a task given on the standard input in a worker process of the process executor, see
+// the isolate flag.
+var workerCmd = &cobra.Command{
+	Use:    "worker",
+	Short:  "execute a task given by the process executor",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// The standard output carries the alerts and the result of the task, the logs go to the standard error.
+		output := os.Stdout
+		os.Stdout = os.Stderr
+		logging.InitLoggingWithParams("info", "console")
+		if err := executor.RunWorker(os.Stdin, output); err != nil {
+			logging.SugaredLogger.Fatalf("Failed to execute task: %s", err)
+		}
+	},
+}
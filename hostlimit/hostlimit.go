@@ -0,0 +1,179 @@
+package hostlimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits are the limits of the requests sent to a host.
+type Limits struct {
+	// Concurrency is the maximum number of concurrent requests to the host, zero means no limit.
+	Concurrency int `mapstructure:"concurrency"`
+	// Delay is the minimum delay between the start of two requests to the host.
+	Delay time.Duration `mapstructure:"delay"`
+}
+
+// hostState holds the state of the requests sent to a host.
+type hostState struct {
+	// slots holds a value for each request in progress, it is nil when the concurrency is not limited.
+	slots chan struct{}
+	// nextStart is the earliest time at which the next request may start.
+	nextStart time.Time
+}
+
+// Limiter enforces the request limits of each host.
+type Limiter struct {
+	defaults   Limits
+	hostLimits map[string]Limits
+	mutex      sync.Mutex
+	hosts      map[string]*hostState
+}
+
+// New returns a new Limiter which applies the default limits to all hosts except the ones in hostLimits.
+func New(defaults Limits, hostLimits map[string]Limits) *Limiter {
+	limiter := &Limiter{
+		defaults:   defaults,
+		hostLimits: make(map[string]Limits, len(hostLimits)),
+		hosts:      make(map[string]*hostState),
+	}
+	for host, limits := range hostLimits {
+		limiter.hostLimits[strings.ToLower(host)] = limits
+	}
+	return limiter
+}
+
+// Limits returns the limits of the host.
+func (l *Limiter) Limits(host string) Limits {
+	if limits, ok := l.hostLimits[strings.ToLower(host)]; ok {
+		return limits
+	}
+	return l.defaults
+}
+
+// Acquire waits until a request to the host may start and returns the function which must be called when the
+// request is done. It returns an error if the context is done before the request may start.
+func (l *Limiter) Acquire(ctx context.Context, host string) (func(), error) {
+	limits := l.Limits(host)
+	if limits.Concurrency <= 0 && limits.Delay <= 0 {
+		return func() {}, nil
+	}
+	state := l.hostState(strings.ToLower(host), limits)
+
+	var release = func() {}
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-state.slots })
+		}
+	}
+
+	// Reserve the start time of the request, then wait for it.
+	l.mutex.Lock()
+	start := time.Now()
+	if state.nextStart.After(start) {
+		start = state.nextStart
+	}
+	state.nextStart = start.Add(limits.Delay)
+	l.mutex.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// hostState returns the state of the host, it is created on the first request.
+func (l *Limiter) hostState(host string, limits Limits) *hostState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{}
+		if limits.Concurrency > 0 {
+			state.slots = make(chan struct{}, limits.Concurrency)
+		}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// limiterKey is the context key of the Limiter.
+type limiterKey struct{}
+
+// NewContext returns a copy of the context which carries the limiter.
+func NewContext(ctx context.Context, limiter *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, limiter)
+}
+
+// FromContext returns the limiter carried by the context, or nil.
+func FromContext(ctx context.Context) *Limiter {
+	limiter, _ := ctx.Value(limiterKey{}).(*Limiter)
+	return limiter
+}
+
+// transport is a http.RoundTripper which applies the limits of the Limiter carried by the request context.
+type transport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip waits until the request may start and sends it. The request counts towards the concurrency of its host
+// until the response body is closed.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := FromContext(req.Context())
+	if limiter == nil {
+		return t.base.RoundTrip(req)
+	}
+	release, err := limiter.Acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody is a response body which releases its request when it is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body and releases the request.
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// Client returns a copy of the client whose requests are limited by the Limiter carried by their context.
+func Client(client *http.Client) *http.Client {
+	limitedClient := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if _, ok := base.(*transport); !ok {
+		limitedClient.Transport = &transport{base: base}
+	}
+	return &limitedClient
+}
@@ -0,0 +1,109 @@
+package hostlimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Limiter_Limits(t *testing.T) {
+	limiter := New(Limits{Concurrency: 2}, map[string]Limits{"Shop.Example.com": {Concurrency: 1, Delay: time.Second}})
+	assert.Equal(t, Limits{Concurrency: 2}, limiter.Limits("jobs.example.com"))
+	assert.Equal(t, Limits{Concurrency: 1, Delay: time.Second}, limiter.Limits("shop.example.com"))
+}
+
+func Test_Limiter_Concurrency(t *testing.T) {
+	limiter := New(Limits{Concurrency: 1}, nil)
+
+	release, err := limiter.Acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+
+	// The second request waits for the first one, requests to other hosts do not.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	otherRelease, err := limiter.Acquire(context.Background(), "example.org")
+	assert.NoError(t, err)
+	otherRelease()
+
+	release()
+	release()
+	release, err = limiter.Acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+	release()
+}
+
+func Test_Limiter_Delay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	limiter := New(Limits{}, map[string]Limits{"example.com": {Delay: delay}})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.com")
+		assert.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 2*delay)
+
+	// Hosts without limits are not delayed.
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.org")
+		assert.NoError(t, err)
+		release()
+	}
+	assert.Less(t, time.Since(start), delay)
+}
+
+func Test_Client(t *testing.T) {
+	var mutex sync.Mutex
+	var inFlight, maxInFlight int
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		inFlight += 1
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		inFlight -= 1
+		mutex.Unlock()
+	}))
+	defer testServer.Close()
+
+	client := Client(http.DefaultClient)
+	assert.Same(t, client.Transport, Client(client).Transport)
+	ctx := NewContext(context.Background(), New(Limits{Concurrency: 1}, nil))
+	assert.NotNil(t, FromContext(ctx))
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, testServer.URL, nil)
+			resp, err := client.Do(req)
+			if assert.NoError(t, err) {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+	waitGroup.Wait()
+	assert.Equal(t, 1, maxInFlight)
+}
+
+func Test_Client_NoLimiter(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer testServer.Close()
+
+	resp, err := Client(http.DefaultClient).Get(testServer.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+}
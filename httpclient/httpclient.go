@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Options are the options for building the shared http client.
+type Options struct {
+	// Proxy is the proxy url, the supported schemes are http, https and socks5.
+	Proxy string `mapstructure:"proxy"`
+	// CaBundle is the path to a PEM bundle with trusted root certificates, used instead of the system roots.
+	CaBundle string `mapstructure:"ca_bundle"`
+	// ClientCertificate is the path to a PEM client certificate.
+	ClientCertificate string `mapstructure:"client_certificate"`
+	// ClientKey is the path to the PEM private key of the client certificate.
+	ClientKey string `mapstructure:"client_key"`
+	// InsecureSkipVerify disables the verification of server certificates.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+	// MaxIdleConnections is the maximum number of idle connections kept open.
+	MaxIdleConnections int `mapstructure:"max_idle_connections"`
+	// MaxIdleConnectionsPerHost is the maximum number of idle connections kept open per host.
+	MaxIdleConnectionsPerHost int `mapstructure:"max_idle_connections_per_host"`
+	// IdleConnectionTimeout is the number of seconds an idle connection is kept open.
+	IdleConnectionTimeout int `mapstructure:"idle_connection_timeout"`
+	// MaxConnectionsPerHost is the maximum number of concurrent connections per host, 0 means no limit.
+	MaxConnectionsPerHost int `mapstructure:"max_connections_per_host"`
+}
+
+// DefaultOptions returns the options used when the workload has no http section.
+func DefaultOptions() Options {
+	return Options{
+		MaxIdleConnections:        100,
+		MaxIdleConnectionsPerHost: 2,
+		IdleConnectionTimeout:     90,
+	}
+}
+
+// OptionsFromMap builds Options from the http section of a workload. Missing keys keep the default value.
+func OptionsFromMap(data map[string]any) (Options, error) {
+	options := DefaultOptions()
+	for key, value := range data {
+		var ok bool
+		switch key {
+		case "proxy":
+			options.Proxy, ok = value.(string)
+		case "ca_bundle":
+			options.CaBundle, ok = value.(string)
+		case "client_certificate":
+			options.ClientCertificate, ok = value.(string)
+		case "client_key":
+			options.ClientKey, ok = value.(string)
+		case "insecure_skip_verify":
+			options.InsecureSkipVerify, ok = value.(bool)
+		case "max_idle_connections":
+			options.MaxIdleConnections, ok = value.(int)
+		case "max_idle_connections_per_host":
+			options.MaxIdleConnectionsPerHost, ok = value.(int)
+		case "idle_connection_timeout":
+			options.IdleConnectionTimeout, ok = value.(int)
+		case "max_connections_per_host":
+			options.MaxConnectionsPerHost, ok = value.(int)
+		default:
+			return options, errors.New(fmt.Sprintf("unknown http option '%s'", key))
+		}
+		if !ok {
+			return options, errors.New(fmt.Sprintf("invalid value for http option '%s': %v", key, value))
+		}
+	}
+	return options, options.Validate()
+}
+
+// Validate validates the Options, returns an error on invalid options.
+func (o *Options) Validate() error {
+	if o.Proxy != "" {
+		proxyUrl, err := url.Parse(o.Proxy)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid proxy %s: %s", o.Proxy, err))
+		}
+		if proxyUrl.Scheme != "http" && proxyUrl.Scheme != "https" && proxyUrl.Scheme != "socks5" {
+			return errors.New(fmt.Sprintf("invalid proxy schema for %s", o.Proxy))
+		}
+	}
+	if (o.ClientCertificate == "") != (o.ClientKey == "") {
+		return errors.New("client_certificate and client_key must be given together")
+	}
+	if o.MaxIdleConnections < 0 || o.MaxIdleConnectionsPerHost < 0 || o.IdleConnectionTimeout < 0 || o.MaxConnectionsPerHost < 0 {
+		return errors.New("connection limits cannot be negative")
+	}
+	return nil
+}
+
+// New returns a new http client built from the given options.
+func New(options Options) (*http.Client, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if options.CaBundle != "" {
+		pemData, err := os.ReadFile(options.CaBundle)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("failed to read ca_bundle %s: %s", options.CaBundle, err))
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pemData) {
+			return nil, errors.New(fmt.Sprintf("no certificates found in ca_bundle %s", options.CaBundle))
+		}
+	}
+	if options.ClientCertificate != "" {
+		certificate, err := tls.LoadX509KeyPair(options.ClientCertificate, options.ClientKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("failed to load client certificate: %s", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConns = options.MaxIdleConnections
+	transport.MaxIdleConnsPerHost = options.MaxIdleConnectionsPerHost
+	transport.IdleConnTimeout = time.Duration(options.IdleConnectionTimeout) * time.Second
+	transport.MaxConnsPerHost = options.MaxConnectionsPerHost
+	if options.Proxy != "" {
+		proxyUrl, _ := url.Parse(options.Proxy)
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
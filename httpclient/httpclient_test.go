@@ -0,0 +1,157 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_OptionsFromMap(t *testing.T) {
+	options, err := OptionsFromMap(map[string]any{
+		"proxy":                    "socks5://127.0.0.1:1080",
+		"insecure_skip_verify":     true,
+		"max_connections_per_host": 2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Options{
+		Proxy:                     "socks5://127.0.0.1:1080",
+		InsecureSkipVerify:        true,
+		MaxIdleConnections:        100,
+		MaxIdleConnectionsPerHost: 2,
+		IdleConnectionTimeout:     90,
+		MaxConnectionsPerHost:     2,
+	}, options)
+}
+
+func Test_OptionsFromMap_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Data     map[string]any
+	}{
+		{"UnknownOption", map[string]any{"proxies": "http://proxy"}},
+		{"InvalidType", map[string]any{"max_connections_per_host": "2"}},
+		{"InvalidProxySchema", map[string]any{"proxy": "ftp://proxy"}},
+		{"ClientCertificateWithoutKey", map[string]any{"client_certificate": "cert.pem"}},
+		{"NegativeLimit", map[string]any{"max_idle_connections": -1}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			_, err := OptionsFromMap(tv.Data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_New_Proxy(t *testing.T) {
+	var proxiedUrl string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		proxiedUrl = request.URL.String()
+	}))
+	defer proxyServer.Close()
+
+	options := DefaultOptions()
+	options.Proxy = proxyServer.URL
+	client, err := New(options)
+	assert.NoError(t, err)
+
+	resp, err := client.Get("http://hotalert.test/page")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "http://hotalert.test/page", proxiedUrl)
+}
+
+func Test_New_TLS(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer testServer.Close()
+
+	caBundlePath := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caBundlePath, pemData, 0600))
+
+	var tests = []struct {
+		TestName      string
+		Options       Options
+		ExpectedError bool
+	}{
+		{"UntrustedServer", DefaultOptions(), true},
+		{"CaBundle", Options{CaBundle: caBundlePath}, false},
+		{"InsecureSkipVerify", Options{InsecureSkipVerify: true}, false},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			client, err := New(tv.Options)
+			assert.NoError(t, err)
+			resp, err := client.Get(testServer.URL)
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				_ = resp.Body.Close()
+			}
+		})
+	}
+}
+
+func Test_New_ClientCertificate(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if len(request.TLS.PeerCertificates) == 0 {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = writer.Write([]byte(request.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	testServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	// Generate a self-signed client certificate.
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hotalert"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certificateData, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	keyData, err := x509.MarshalECPrivateKey(privateKey)
+	assert.NoError(t, err)
+
+	directory := t.TempDir()
+	certificatePath := filepath.Join(directory, "client.pem")
+	keyPath := filepath.Join(directory, "client-key.pem")
+	assert.NoError(t, os.WriteFile(certificatePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateData}), 0600))
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyData}), 0600))
+
+	client, err := New(Options{InsecureSkipVerify: true, ClientCertificate: certificatePath, ClientKey: keyPath})
+	assert.NoError(t, err)
+	resp, err := client.Get(testServer.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func Test_New_Errors(t *testing.T) {
+	_, err := New(Options{CaBundle: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+	_, err = New(Options{ClientCertificate: "missing.pem", ClientKey: "missing-key.pem"})
+	assert.Error(t, err)
+	_, err = New(Options{Proxy: "ftp://proxy"})
+	assert.Error(t, err)
+}
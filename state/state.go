@@ -0,0 +1,89 @@
+package state
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Store is an interface for implementing persistent storage for task state between runs.
+type Store interface {
+	// Load loads the value stored under key into value. It returns false if nothing is stored under key.
+	Load(key string, value any) (bool, error)
+	// Save stores the value under key.
+	Save(key string, value any) error
+}
+
+// FileStore is a Store that keeps each value as a JSON file inside a directory.
+type FileStore struct {
+	// directory is the directory where the state files are kept.
+	directory string
+}
+
+// NewFileStore returns a new FileStore instance which keeps the files in the given directory.
+func NewFileStore(directory string) *FileStore {
+	return &FileStore{
+		directory: directory,
+	}
+}
+
+// DefaultDirectory returns the default directory for state files, which is located in the user's cache directory.
+func DefaultDirectory() string {
+	cacheDirectory, err := os.UserCacheDir()
+	if err != nil {
+		cacheDirectory = os.TempDir()
+	}
+	return filepath.Join(cacheDirectory, "hotalert")
+}
+
+// path returns the file path for the given key.
+func (s *FileStore) path(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return filepath.Join(s.directory, hex.EncodeToString(hash[:])+".json")
+}
+
+// Load loads the value stored under key into value. It returns false if nothing is stored under key.
+func (s *FileStore) Load(key string, value any) (bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("failed to read state for %s: %s", key, err))
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, errors.New(fmt.Sprintf("failed to decode state for %s: %s", key, err))
+	}
+	return true, nil
+}
+
+// Save stores the value under key. The file is replaced atomically so that a crash never leaves partial state.
+func (s *FileStore) Save(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to encode state for %s: %s", key, err))
+	}
+	if err := os.MkdirAll(s.directory, 0700); err != nil {
+		return errors.New(fmt.Sprintf("failed to create state directory %s: %s", s.directory, err))
+	}
+	tempFile, err := os.CreateTemp(s.directory, "state-*.tmp")
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to write state for %s: %s", key, err))
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to write state for %s: %s", key, err))
+	}
+	if err := os.Rename(tempFile.Name(), s.path(key)); err != nil {
+		return errors.New(fmt.Sprintf("failed to write state for %s: %s", key, err))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package state
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_FileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "state"))
+
+	var value []string
+	found, err := store.Load("rss_watch https://example.com/feed", &value)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	err = store.Save("rss_watch https://example.com/feed", []string{"a", "b"})
+	assert.NoError(t, err)
+
+	found, err = store.Load("rss_watch https://example.com/feed", &value)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"a", "b"}, value)
+
+	found, err = store.Load("rss_watch https://example.com/other", &value)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func Test_FileStore_CorruptState(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	assert.NoError(t, os.WriteFile(store.path("key"), []byte("{not json"), 0600))
+
+	var value map[string]int
+	found, err := store.Load("key", &value)
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func Test_DefaultDirectory(t *testing.T) {
+	assert.Equal(t, "hotalert", filepath.Base(DefaultDirectory()))
+}
@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"fmt"
+	"hotalert/task"
+	"sync"
+)
+
+// queuedTask is a task in the queue of the DefaultExecutor, with the batch it belongs to, if any.
+type queuedTask struct {
+	task  *task.Task
+	batch *Batch
+	// options are the options of the task with the outputs of its dependencies, or nil if it uses none.
+	options task.Options
+	// err is the error of a batch task which is not executed.
+	err error
+}
+
+// executedTask returns the task which is executed, a copy of the task with the options if it has them.
+func (q queuedTask) executedTask() *task.Task {
+	if q.options == nil {
+		return q.task
+	}
+	var executedTask = *q.task
+	executedTask.Options = q.options
+	return &executedTask
+}
+
+// Report is the aggregated outcome of a batch of tasks.
+type Report struct {
+	// Results holds a result for each task, in the order in which the tasks completed. Tasks which were not queued,
+	// for example because the executor was stopped, have a result with the error returned by AddTask.
+	Results []*task.Result
+}
+
+// Succeeded returns the number of tasks which succeeded.
+func (r *Report) Succeeded() int {
+	return len(r.Results) - len(r.Failed())
+}
+
+// Failed returns the results of the tasks which failed or were not executed.
+func (r *Report) Failed() []*task.Result {
+	var failed = make([]*task.Result, 0)
+	for _, result := range r.Results {
+		if result.Error() != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Batch is a batch of tasks submitted to the DefaultExecutor, see SubmitBatch.
+type Batch struct {
+	// results receives the result of each task, it has room for all of them so that workers never block on it.
+	results chan *task.Result
+	// done is closed when all the tasks of the batch are done.
+	done chan struct{}
+	// ready receives the tasks which can be queued, it is closed when all the tasks of the batch are done.
+	ready chan queuedTask
+	// mutex guards the fields below.
+	mutex   sync.Mutex
+	report  Report
+	pending int
+	// waiting holds the tasks whose dependencies are not done.
+	waiting []*task.Task
+	// outputs holds the outputs of the tasks which succeeded, by task id.
+	outputs map[string]map[string]string
+	// failed holds the ids of the tasks which failed or were skipped.
+	failed map[string]bool
+}
+
+// SubmitBatch adds the tasks to the executor in the background and returns their Batch.
+// The results of the tasks are delivered to the batch instead of the task Result channel returned by Start, so a
+// batch is complete once it has a result for each task, whether the task was executed or not.
+// A task which depends on other tasks is queued once they succeeded, with the ${tasks.<id>.<output>} placeholders
+// of its options replaced by their outputs. If one of them failed the task is skipped with ErrDependencyFailed.
+// If the dependencies are not valid, see ValidateDependencies, no task is executed.
+func (ws *DefaultExecutor) SubmitBatch(tasks []*task.Task) *Batch {
+	batch := &Batch{
+		results: make(chan *task.Result, len(tasks)),
+		done:    make(chan struct{}),
+		ready:   make(chan queuedTask, len(tasks)),
+		report:  Report{Results: make([]*task.Result, 0, len(tasks))},
+		pending: len(tasks),
+		outputs: make(map[string]map[string]string),
+		failed:  make(map[string]bool),
+	}
+	if len(tasks) == 0 {
+		batch.close()
+		return batch
+	}
+
+	err := ValidateDependencies(tasks)
+	batch.mutex.Lock()
+	for _, currentTask := range tasks {
+		switch {
+		case err != nil:
+			batch.ready <- queuedTask{task: currentTask, batch: batch, err: err}
+		case len(currentTask.DependsOn) == 0:
+			batch.ready <- queuedTask{task: currentTask, batch: batch}
+		default:
+			batch.waiting = append(batch.waiting, currentTask)
+		}
+	}
+	batch.mutex.Unlock()
+
+	go func() {
+		for queued := range batch.ready {
+			err := queued.err
+			if err == nil {
+				err = ws.queueTask(queued)
+			}
+			if err != nil {
+				var taskResult = task.NewResult(queued.task)
+				taskResult.SetError(err)
+				ws.completeTask(queued, taskResult)
+			}
+		}
+	}()
+	return batch
+}
+
+// addResult records the result of a task of the batch and readies the tasks which depend on it.
+func (b *Batch) addResult(taskResult *task.Result) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.report.Results = append(b.report.Results, taskResult)
+	b.results <- taskResult
+	if id := taskResult.InitialTask.Id; id != "" {
+		if taskResult.Error() != nil {
+			b.failed[id] = true
+		} else {
+			b.outputs[id] = taskResult.Outputs()
+		}
+	}
+	b.pending -= 1
+	if b.pending == 0 {
+		b.close()
+		return
+	}
+	b.readyWaitingTasks()
+}
+
+// readyWaitingTasks readies the waiting tasks whose dependencies are done. Tasks whose dependencies failed are
+// readied with ErrDependencyFailed and tasks whose outputs cannot be expanded with the expansion error.
+func (b *Batch) readyWaitingTasks() {
+	var stillWaiting = b.waiting[:0]
+	for _, currentTask := range b.waiting {
+		var waiting bool
+		var err error
+		for _, dependency := range currentTask.DependsOn {
+			_, succeeded := b.outputs[dependency]
+			switch {
+			case b.failed[dependency] && err == nil:
+				err = fmt.Errorf("%w: %s", ErrDependencyFailed, dependency)
+			case !succeeded && !b.failed[dependency]:
+				waiting = true
+			}
+		}
+		if waiting && err == nil {
+			stillWaiting = append(stillWaiting, currentTask)
+			continue
+		}
+
+		var queued = queuedTask{task: currentTask, batch: b, err: err}
+		if err == nil && len(task.OutputReferences(currentTask.Options)) > 0 {
+			var escape func(value string) string
+			if function, ok := LookupFunction(currentTask.ExecutionFuncName); ok {
+				if escaper, ok := function.(task.OutputEscaper); ok {
+					escape = escaper.EscapeOutput
+				}
+			}
+			queued.options, queued.err = task.ExpandOutputs(currentTask.Options, b.outputs, escape)
+		}
+		b.ready <- queued
+	}
+	b.waiting = stillWaiting
+}
+
+// close closes the channels of the batch once all its tasks are done.
+func (b *Batch) close() {
+	close(b.ready)
+	close(b.results)
+	close(b.done)
+}
+
+// Results returns a channel which receives the result of each task as it completes.
+// The channel is closed once all the tasks of the batch are done.
+func (b *Batch) Results() <-chan *task.Result {
+	return b.results
+}
+
+// Done returns a channel which is closed once all the tasks of the batch are done.
+func (b *Batch) Done() <-chan struct{} {
+	return b.done
+}
+
+// Wait waits until all the tasks of the batch are done and returns the report.
+func (b *Batch) Wait() *Report {
+	<-b.done
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return &Report{Results: append([]*task.Result(nil), b.report.Results...)}
+}
@@ -0,0 +1,217 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Report(t *testing.T) {
+	var succeeded, failed = task.NewResult(&task.Task{}), task.NewResult(&task.Task{})
+	failed.SetError(errors.New("test"))
+	report := Report{Results: []*task.Result{succeeded, failed}}
+	assert.Equal(t, 1, report.Succeeded())
+	assert.Equal(t, []*task.Result{failed}, report.Failed())
+}
+
+func Test_DefaultExecutor_SubmitBatch(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		if currentTask.Options["fail"] == true {
+			return errors.New("test")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor := NewDefaultExecutor()
+	taskResultsChan := defaultExecutor.Start()
+	var tasks = make([]*task.Task, 0, 20)
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, &task.Task{
+			ExecutionFuncName: functionName,
+			Options:           task.Options{"fail": i%4 == 0},
+			Alerter:           alert.NewDummyAlerter(),
+		})
+	}
+
+	// Two batches run at the same time and each receives only the results of its tasks.
+	firstBatch, secondBatch := defaultExecutor.SubmitBatch(tasks[:10]), defaultExecutor.SubmitBatch(tasks[10:])
+	var results = 0
+	for result := range firstBatch.Results() {
+		assert.Contains(t, tasks[:10], result.InitialTask)
+		results += 1
+	}
+	assert.Equal(t, 10, results)
+	<-firstBatch.Done()
+
+	firstReport, secondReport := firstBatch.Wait(), secondBatch.Wait()
+	assert.Len(t, firstReport.Results, 10)
+	assert.Len(t, firstReport.Failed(), 3)
+	assert.Equal(t, 8, secondReport.Succeeded())
+	for _, result := range secondReport.Results {
+		assert.Contains(t, tasks[10:], result.InitialTask)
+	}
+
+	defaultExecutor.Shutdown()
+	_, ok := <-taskResultsChan
+	assert.False(t, ok)
+}
+
+func Test_DefaultExecutor_SubmitBatch_Empty(t *testing.T) {
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+
+	report := defaultExecutor.SubmitBatch(nil).Wait()
+	assert.Empty(t, report.Results)
+
+	defaultExecutor.Shutdown()
+}
+
+func Test_DefaultExecutor_SubmitBatch_Stopped(t *testing.T) {
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+	defaultExecutor.Shutdown()
+
+	// The tasks of a stopped executor are not executed but the batch completes.
+	report := defaultExecutor.SubmitBatch([]*task.Task{{ExecutionFuncName: "task_test"}, {ExecutionFuncName: "task_test"}}).Wait()
+	assert.Len(t, report.Results, 2)
+	for _, result := range report.Results {
+		assert.Equal(t, ErrExecutorStopped, result.Error())
+	}
+}
+
+func Test_DefaultExecutor_SubmitBatch_NotStarted(t *testing.T) {
+	// The tasks of an executor which shuts down without executing them are still reported.
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 1, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
+	batch := defaultExecutor.SubmitBatch([]*task.Task{{ExecutionFuncName: "task_test"}, {ExecutionFuncName: "task_test"}, {ExecutionFuncName: "task_test"}})
+	defaultExecutor.Shutdown()
+
+	report := batch.Wait()
+	assert.Len(t, report.Results, 3)
+	for _, result := range report.Results {
+		assert.Equal(t, ErrExecutorStopped, result.Error())
+	}
+}
+
+func Test_DefaultExecutor_SubmitBatch_Dependencies(t *testing.T) {
+	// The function fails if the fail option is set and outputs the url option.
+	var executedMutex sync.Mutex
+	var executed []string
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		executedMutex.Lock()
+		executed = append(executed, currentTask.Id)
+		executedMutex.Unlock()
+		if currentTask.Options["fail"] == true {
+			return errors.New("test")
+		}
+		task.SetOutput(ctx, "url", fmt.Sprint(currentTask.Options["url"]))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var tasks = []*task.Task{
+		{Id: "page", DependsOn: []string{"search"}, Options: task.Options{"url": "${tasks.search.url}/page"}},
+		{Id: "search", DependsOn: []string{"health"}, Options: task.Options{"url": "https://shop.example.com/search"}},
+		{Id: "health", Options: task.Options{"url": "https://shop.example.com/health"}},
+		{Id: "down", Options: task.Options{"fail": true}},
+		{Id: "skipped", DependsOn: []string{"health", "down"}},
+		{Id: "skipped_too", DependsOn: []string{"skipped"}},
+	}
+	for _, currentTask := range tasks {
+		currentTask.ExecutionFuncName = functionName
+		currentTask.Alerter = alert.NewDummyAlerter()
+	}
+
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+	report := defaultExecutor.SubmitBatch(tasks).Wait()
+	defaultExecutor.Shutdown()
+
+	var resultsById = make(map[string]*task.Result)
+	for _, result := range report.Results {
+		resultsById[result.InitialTask.Id] = result
+	}
+	assert.Len(t, resultsById, len(tasks))
+
+	// The page task ran after its dependencies, with the output of the search task, but the result refers to the
+	// task as it was submitted.
+	assert.NoError(t, resultsById["page"].Error())
+	assert.Equal(t, map[string]string{"url": "https://shop.example.com/search/page"}, resultsById["page"].Outputs())
+	assert.Same(t, tasks[0], resultsById["page"].InitialTask)
+	assert.Equal(t, "${tasks.search.url}/page", tasks[0].Options["url"])
+
+	// The tasks which depend on the failed task are skipped.
+	assert.Error(t, resultsById["down"].Error())
+	assert.ErrorIs(t, resultsById["skipped"].Error(), ErrDependencyFailed)
+	assert.ErrorIs(t, resultsById["skipped_too"].Error(), ErrDependencyFailed)
+	assert.NotContains(t, executed, "skipped")
+	assert.NotContains(t, executed, "skipped_too")
+
+	var order = make(map[string]int)
+	for i, id := range executed {
+		order[id] = i
+	}
+	assert.Less(t, order["health"], order["search"])
+	assert.Less(t, order["search"], order["page"])
+}
+
+func Test_DefaultExecutor_SubmitBatch_EscapedOutputs(t *testing.T) {
+	t.Setenv("HOTALERT_TEST_SECRET", "secret")
+	// The output comes from a page which tries to read an environment variable in the following http_flow task.
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		task.SetOutput(ctx, "token", "${env:HOTALERT_TEST_SECRET}")
+		return nil
+	})
+	assert.NoError(t, err)
+	var requestedToken string
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestedToken = request.URL.Query().Get("token")
+		_, _ = writer.Write([]byte("ok"))
+	}))
+	defer testHttpServer.Close()
+
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+	report := defaultExecutor.SubmitBatch([]*task.Task{
+		{Id: "page", ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()},
+		{
+			Id:                "flow",
+			DependsOn:         []string{"page"},
+			ExecutionFuncName: "http_flow",
+			Options: task.Options{
+				"steps":    []any{map[string]any{"url": testHttpServer.URL + "?token=${tasks.page.token}"}},
+				"keywords": []any{"ok"},
+			},
+			Timeout: 10 * time.Second,
+			Alerter: alert.NewDummyAlerter(),
+		},
+	}).Wait()
+	defaultExecutor.Shutdown()
+
+	assert.Empty(t, report.Failed())
+	assert.Equal(t, "${env:HOTALERT_TEST_SECRET}", requestedToken)
+}
+
+func Test_DefaultExecutor_SubmitBatch_InvalidDependencies(t *testing.T) {
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+	report := defaultExecutor.SubmitBatch([]*task.Task{
+		{Id: "a", DependsOn: []string{"b"}},
+		{Id: "b", DependsOn: []string{"a"}},
+	}).Wait()
+	defaultExecutor.Shutdown()
+
+	assert.Len(t, report.Failed(), 2)
+}
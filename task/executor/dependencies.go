@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"hotalert/task"
+	"strings"
+)
+
+// ErrDependencyFailed is the error of the tasks which were not executed because a task they depend on failed.
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// ValidateDependencies returns an error if the task ids are not unique, if a task depends on an unknown task, if a
+// task refers to the outputs of a task it does not depend on or if the dependencies have a cycle.
+func ValidateDependencies(tasks []*task.Task) error {
+	var tasksById = make(map[string]*task.Task, len(tasks))
+	for _, currentTask := range tasks {
+		if currentTask.Id == "" {
+			continue
+		}
+		if _, ok := tasksById[currentTask.Id]; ok {
+			return errors.New(fmt.Sprintf("duplicate task id '%s'", currentTask.Id))
+		}
+		tasksById[currentTask.Id] = currentTask
+	}
+
+	for _, currentTask := range tasks {
+		for _, dependency := range currentTask.DependsOn {
+			if _, ok := tasksById[dependency]; !ok {
+				return errors.New(fmt.Sprintf("task '%s' depends on unknown task '%s'", taskName(currentTask), dependency))
+			}
+		}
+		for _, reference := range task.OutputReferences(currentTask.Options) {
+			if !containsString(currentTask.DependsOn, reference) {
+				return errors.New(fmt.Sprintf("task '%s' uses the outputs of task '%s' without depending on it", taskName(currentTask), reference))
+			}
+		}
+	}
+
+	// Depth first search, a task which is reached again while it is on the path closes a cycle.
+	const (
+		unvisited = iota
+		onPath
+		visited
+	)
+	var states = make(map[string]int, len(tasksById))
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch states[id] {
+		case onPath:
+			return errors.New(fmt.Sprintf("dependency cycle %s -> %s", strings.Join(path, " -> "), id))
+		case visited:
+			return nil
+		}
+		states[id] = onPath
+		path = append(path, id)
+		for _, dependency := range tasksById[id].DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		states[id] = visited
+		return nil
+	}
+	for _, currentTask := range tasks {
+		if currentTask.Id != "" {
+			if err := visit(currentTask.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// taskName returns the id of the task, or its function name if it has no id.
+func taskName(currentTask *task.Task) string {
+	if currentTask.Id != "" {
+		return currentTask.Id
+	}
+	return currentTask.ExecutionFuncName
+}
+
+// containsString returns true if the values contain the value.
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
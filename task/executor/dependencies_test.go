@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"testing"
+)
+
+func Test_ValidateDependencies(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Tasks         []*task.Task
+		ExpectedError string
+	}{
+		{"NoDependencies", []*task.Task{{ExecutionFuncName: "web_scrape"}, {ExecutionFuncName: "web_scrape"}}, ""},
+		{"Pipeline", []*task.Task{
+			{Id: "health"},
+			{Id: "search", DependsOn: []string{"health"}},
+			{ExecutionFuncName: "web_scrape", DependsOn: []string{"health", "search"}, Options: task.Options{"url": "${tasks.search.url}"}},
+		}, ""},
+		{"DuplicateId", []*task.Task{{Id: "health"}, {Id: "health"}}, "duplicate task id 'health'"},
+		{"UnknownDependency", []*task.Task{{Id: "page", DependsOn: []string{"health"}}}, "task 'page' depends on unknown task 'health'"},
+		{"ReferenceWithoutDependency", []*task.Task{
+			{Id: "search"},
+			{Id: "page", Options: task.Options{"url": "${tasks.search.url}"}},
+		}, "task 'page' uses the outputs of task 'search' without depending on it"},
+		{"SelfCycle", []*task.Task{{Id: "page", DependsOn: []string{"page"}}}, "dependency cycle page -> page"},
+		{"Cycle", []*task.Task{
+			{Id: "a", DependsOn: []string{"c"}},
+			{Id: "b", DependsOn: []string{"a"}},
+			{Id: "c", DependsOn: []string{"b"}},
+		}, "dependency cycle a -> c -> b -> a"},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			err := ValidateDependencies(tv.Tasks)
+			if tv.ExpectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tv.ExpectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"hotalert/taskqueue"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queueRetryDelay is the delay before popping again from a queue which returned an error.
+var queueRetryDelay = time.Second
+
+// DistributedOptions are the options of the queue shared by the DistributedExecutor and QueueWorker instances.
+type DistributedOptions struct {
+	// Queue is the queue which carries the tasks and their results.
+	Queue taskqueue.Queue
+	// Name is the prefix of the queue names, the executors and the workers with the same name share their tasks.
+	Name string
+	// Lease is the duration for which a task is leased to a worker. The worker renews the lease while the task runs,
+	// the task is delivered to another worker when the lease expires, for example because the worker crashed.
+	Lease time.Duration
+	// MaxDeliveries is the maximum number of times a task is delivered to a worker, the task fails when its workers
+	// keep stopping before completing it.
+	MaxDeliveries int
+}
+
+// DefaultDistributedOptions returns the default options for the given queue.
+func DefaultDistributedOptions(queue taskqueue.Queue) DistributedOptions {
+	return DistributedOptions{
+		Queue:         queue,
+		Name:          "hotalert",
+		Lease:         30 * time.Second,
+		MaxDeliveries: 3,
+	}
+}
+
+// Validate returns an error if the options are not valid.
+func (o *DistributedOptions) Validate() error {
+	if o.Queue == nil {
+		return errors.New("invalid queue, cannot be nil")
+	}
+	if o.Name == "" {
+		return errors.New("invalid queue name, cannot be empty")
+	}
+	if o.Lease <= 0 {
+		return errors.New(fmt.Sprintf("invalid lease %s, must be positive", o.Lease))
+	}
+	if o.MaxDeliveries < 1 {
+		return errors.New(fmt.Sprintf("invalid max deliveries %d, must be at least 1", o.MaxDeliveries))
+	}
+	return nil
+}
+
+// tasksQueue returns the name of the queue of the tasks.
+func (o *DistributedOptions) tasksQueue() string {
+	return o.Name + ":tasks"
+}
+
+// distributedTask is a task pushed by a DistributedExecutor for the workers.
+type distributedTask struct {
+	Id string `json:"id"`
+	// ReplyTo is the queue of the executor results.
+	ReplyTo string `json:"reply_to"`
+	// Deadline is the time at which the executor stops waiting for the result, it is zero for tasks without timeout.
+	Deadline time.Time     `json:"deadline"`
+	Priority int           `json:"priority,omitempty"`
+	Workload string        `json:"workload,omitempty"`
+	Request  workerRequest `json:"request"`
+}
+
+// distributedResult is the result of a task pushed by a QueueWorker for the executor of the task.
+type distributedResult struct {
+	TaskId string `json:"task_id"`
+	// Alerts are the keywords of the alerts posted by the task, which the executor posts with the task alerter.
+	Alerts [][]string    `json:"alerts,omitempty"`
+	Result workerMessage `json:"result"`
+}
+
+// DistributedExecutor is an Executor which pushes its tasks to a queue shared with QueueWorker instances, possibly
+// running on other machines, and waits for their results.
+// The alerts of the tasks are posted by the executor with the task alerters, the tasks are executed by the workers
+// with their own host limits. The task functions must be registered in the worker programs as well.
+// The queue, the batches, the retries and the middleware work as with the DefaultExecutor, the workers limit the
+// number of tasks executed at the same time.
+type DistributedExecutor struct {
+	*DefaultExecutor
+	distributedOptions DistributedOptions
+	// replyQueue is the queue of the results of the tasks of the executor.
+	replyQueue string
+	// lastId is the id of the last pushed task.
+	lastId uint64
+	// waiters holds the channels which receive the result of each pushed task by task id.
+	waiters      map[string]chan *distributedResult
+	waitersMutex sync.Mutex
+	// receiving waits for receiveResults, which stops when stopReceiving is called.
+	receiving     sync.WaitGroup
+	receiveCtx    context.Context
+	stopReceiving context.CancelFunc
+}
+
+// NewDistributedExecutor returns a new instance of DistributedExecutor given its options.
+// It returns an error if the options are invalid.
+func NewDistributedExecutor(options Options, distributedOptions DistributedOptions) (*DistributedExecutor, error) {
+	if err := distributedOptions.Validate(); err != nil {
+		return nil, err
+	}
+	defaultExecutor, err := NewDefaultExecutorWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := randomId()
+	if err != nil {
+		return nil, err
+	}
+	de := &DistributedExecutor{
+		DefaultExecutor:    defaultExecutor,
+		distributedOptions: distributedOptions,
+		replyQueue:         distributedOptions.Name + ":results:" + suffix,
+		waiters:            make(map[string]chan *distributedResult),
+	}
+	de.receiveCtx, de.stopReceiving = context.WithCancel(context.Background())
+	defaultExecutor.functionHandler = func(function task.Function) Handler {
+		return de.runRemote
+	}
+	return de, nil
+}
+
+// runRemote pushes the task to the queue and waits for its result.
+func (de *DistributedExecutor) runRemote(ctx context.Context, currentTask *task.Task) error {
+	var message = distributedTask{
+		Id:       strconv.FormatUint(atomic.AddUint64(&de.lastId, 1), 10),
+		ReplyTo:  de.replyQueue,
+		Priority: currentTask.Priority,
+		Workload: currentTask.Workload,
+		Request: workerRequest{
+			Function:    currentTask.ExecutionFuncName,
+			Options:     currentTask.Options,
+			Timeout:     currentTask.Timeout,
+			HttpOptions: currentTask.HttpOptions,
+		},
+	}
+	if currentTask.Timeout > 0 {
+		message.Deadline = time.Now().Add(currentTask.Timeout)
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to encode the task: %s", err))
+	}
+
+	var reply = make(chan *distributedResult, 1)
+	de.waitersMutex.Lock()
+	de.waiters[message.Id] = reply
+	de.waitersMutex.Unlock()
+	defer func() {
+		de.waitersMutex.Lock()
+		delete(de.waiters, message.Id)
+		de.waitersMutex.Unlock()
+	}()
+
+	if err := de.distributedOptions.Queue.Push(ctx, de.distributedOptions.tasksQueue(), body); err != nil {
+		return errors.New(fmt.Sprintf("failed to push the task: %s", err))
+	}
+	select {
+	case result := <-reply:
+		for _, keywords := range result.Alerts {
+			currentTask.Alerter.PostAlert(ctx, keywords)
+		}
+		for name, value := range result.Result.Outputs {
+			task.SetOutput(ctx, name, value)
+		}
+		return result.Result.err()
+	case <-ctx.Done():
+		return fmt.Errorf("task result not received: %w", ctx.Err())
+	}
+}
+
+// receiveResults pops the results of the tasks of the executor and hands them to runRemote, until stopReceiving is
+// called.
+func (de *DistributedExecutor) receiveResults() {
+	defer de.receiving.Done()
+	queue := de.distributedOptions.Queue
+	for {
+		message, err := queue.Pop(de.receiveCtx, de.replyQueue, de.distributedOptions.Lease)
+		if err != nil {
+			if !waitAfterQueueError(de.receiveCtx, "Failed to receive task results", err) {
+				return
+			}
+			continue
+		}
+		if err := queue.Ack(context.Background(), de.replyQueue, message.Id); err != nil {
+			logging.SugaredLogger.Warnf("Failed to acknowledge task result %s: %s", message.Id, err)
+		}
+		var result distributedResult
+		if err := json.Unmarshal(message.Body, &result); err != nil {
+			logging.SugaredLogger.Errorf("Dropping invalid task result %s: %s", message.Id, err)
+			continue
+		}
+
+		de.waitersMutex.Lock()
+		reply, ok := de.waiters[result.TaskId]
+		de.waitersMutex.Unlock()
+		if !ok {
+			// The task timed out or was cancelled.
+			continue
+		}
+		// A task delivered twice may have two results, the first one is kept.
+		select {
+		case reply <- &result:
+		default:
+		}
+	}
+}
+
+// Start starts the DistributedExecutor.
+// Start returns a receive only channel with task Result.
+func (de *DistributedExecutor) Start() <-chan *task.Result {
+	de.receiving.Add(1)
+	go de.receiveResults()
+	return de.DefaultExecutor.Start()
+}
+
+// Shutdown shuts down the DistributedExecutor, see DefaultExecutor.Shutdown.
+// The tasks already pushed to the queue are skipped by the workers once their timeout elapses. The queue is not
+// closed.
+func (de *DistributedExecutor) Shutdown() {
+	de.DefaultExecutor.Shutdown()
+	de.stopReceiving()
+	de.receiving.Wait()
+}
+
+// QueueWorker executes the tasks pushed to the queue by DistributedExecutor instances with an executor, and pushes
+// back their results.
+// A task is acknowledged once its result is pushed. When the worker stops before, the task is delivered to another
+// worker after its lease expires.
+type QueueWorker struct {
+	executor           Executor
+	distributedOptions DistributedOptions
+	// pulling waits for pullTasks, which stops when stopPulling is called.
+	pulling     sync.WaitGroup
+	pullCtx     context.Context
+	stopPulling context.CancelFunc
+}
+
+// NewQueueWorker returns a new instance of QueueWorker which executes the tasks with the given executor.
+// The executor should block when its task queue is full, so that the worker does not lease more tasks than it
+// executes. It returns an error if the options are invalid.
+func NewQueueWorker(executor Executor, distributedOptions DistributedOptions) (*QueueWorker, error) {
+	if err := distributedOptions.Validate(); err != nil {
+		return nil, err
+	}
+	qw := &QueueWorker{executor: executor, distributedOptions: distributedOptions}
+	qw.pullCtx, qw.stopPulling = context.WithCancel(context.Background())
+	return qw, nil
+}
+
+// Start starts the executor and pulls tasks from the queue until Drain or Shutdown is called.
+func (qw *QueueWorker) Start() {
+	results := qw.executor.Start()
+	go func() {
+		// The results are pushed by the task callbacks.
+		for range results {
+		}
+	}()
+	qw.pulling.Add(1)
+	go qw.pullTasks()
+}
+
+// pullTasks pops tasks from the queue and adds them to the executor, until stopPulling is called.
+func (qw *QueueWorker) pullTasks() {
+	defer qw.pulling.Done()
+	for {
+		message, err := qw.distributedOptions.Queue.Pop(qw.pullCtx, qw.distributedOptions.tasksQueue(), qw.distributedOptions.Lease)
+		if err != nil {
+			if !waitAfterQueueError(qw.pullCtx, "Failed to receive tasks", err) {
+				return
+			}
+			continue
+		}
+		qw.handleTask(message)
+	}
+}
+
+// handleTask adds the task given by the message to the executor, or pushes back an error result when the task cannot
+// be executed.
+func (qw *QueueWorker) handleTask(message *taskqueue.Message) {
+	var request distributedTask
+	if err := json.Unmarshal(message.Body, &request); err != nil {
+		logging.SugaredLogger.Errorf("Dropping invalid task %s: %s", message.Id, err)
+		qw.ack(message)
+		return
+	}
+	if !request.Deadline.IsZero() && time.Now().After(request.Deadline) {
+		logging.SugaredLogger.Warnf("Dropping task %s, its executor stopped waiting for it", request.Request.Function)
+		qw.ack(message)
+		return
+	}
+	if message.Deliveries > qw.distributedOptions.MaxDeliveries {
+		err := errors.New(fmt.Sprintf("task was delivered %d times, its workers stopped before completing it", message.Deliveries-1))
+		qw.reply(message, request, distributedResult{Result: resultMessage(err, nil)})
+		return
+	}
+
+	alerter := &collectingAlerter{}
+	currentTask, err := newWorkerTask(request.Request, alerter)
+	if err != nil {
+		qw.reply(message, request, distributedResult{Result: resultMessage(err, nil)})
+		return
+	}
+	currentTask.Priority = request.Priority
+	currentTask.Workload = request.Workload
+	if !request.Deadline.IsZero() {
+		currentTask.Timeout = time.Until(request.Deadline)
+	}
+
+	stopExtending := qw.extendLease(message)
+	var callback task.Callback = func(result *task.Result) {
+		stopExtending()
+		// The task was not completed because the worker is stopping, leave it to another worker.
+		if errors.Is(result.Error(), ErrExecutorStopped) || errors.Is(result.Error(), context.Canceled) {
+			return
+		}
+		qw.reply(message, request, distributedResult{
+			Alerts: alerter.keywords(),
+			Result: resultMessage(result.Error(), result.Outputs()),
+		})
+	}
+	currentTask.Callback = &callback
+	if err := qw.executor.AddTask(currentTask); err != nil {
+		stopExtending()
+		logging.SugaredLogger.Warnf("Failed to execute task %s, leaving it to another worker: %s", request.Request.Function, err)
+	}
+}
+
+// extendLease renews the lease of the message until the returned function is called.
+func (qw *QueueWorker) extendLease(message *taskqueue.Message) func() {
+	var stop = make(chan struct{})
+	var stopped = make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(qw.distributedOptions.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := qw.distributedOptions.Queue.Extend(context.Background(), qw.distributedOptions.tasksQueue(), message.Id, qw.distributedOptions.Lease)
+				if err != nil {
+					logging.SugaredLogger.Warnf("Failed to extend the lease of task %s: %s", message.Id, err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+		})
+		// The lease is not renewed after the task is acknowledged.
+		<-stopped
+	}
+}
+
+// reply pushes the result of the task to its executor and acknowledges the task.
+// The task is not acknowledged if the result cannot be pushed, so it is delivered again.
+func (qw *QueueWorker) reply(message *taskqueue.Message, request distributedTask, result distributedResult) {
+	result.TaskId = request.Id
+	result.Result.Type = workerMessageResult
+	body, err := json.Marshal(result)
+	if err == nil {
+		err = qw.distributedOptions.Queue.Push(context.Background(), request.ReplyTo, body)
+	}
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to push the result of task %s: %s", request.Request.Function, err)
+		return
+	}
+	qw.ack(message)
+}
+
+// ack acknowledges the task.
+func (qw *QueueWorker) ack(message *taskqueue.Message) {
+	if err := qw.distributedOptions.Queue.Ack(context.Background(), qw.distributedOptions.tasksQueue(), message.Id); err != nil {
+		logging.SugaredLogger.Warnf("Failed to acknowledge task %s: %s", message.Id, err)
+	}
+}
+
+// Drain stops pulling tasks and drains the executor, see Executor.Drain.
+func (qw *QueueWorker) Drain(ctx context.Context) error {
+	qw.stopPulling()
+	qw.pulling.Wait()
+	return qw.executor.Drain(ctx)
+}
+
+// Shutdown stops pulling tasks and shuts down the executor. The tasks in progress are left to other workers.
+func (qw *QueueWorker) Shutdown() {
+	qw.stopPulling()
+	// Shutting down the executor first unblocks the tasks being added.
+	qw.executor.Shutdown()
+	qw.pulling.Wait()
+}
+
+// collectingAlerter is the alerter of the tasks executed by a QueueWorker, it keeps the alerts for the result.
+type collectingAlerter struct {
+	mutex  sync.Mutex
+	alerts [][]string
+}
+
+func (a *collectingAlerter) PostAlert(ctx context.Context, matchedKeywords []string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.alerts = append(a.alerts, matchedKeywords)
+}
+
+// keywords returns the keywords of the posted alerts.
+func (a *collectingAlerter) keywords() [][]string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.alerts
+}
+
+// waitAfterQueueError waits before popping again after the queue returned an error. It returns false when the
+// context is done or the queue is closed, to stop popping.
+func waitAfterQueueError(ctx context.Context, message string, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, taskqueue.ErrQueueClosed) {
+		return false
+	}
+	logging.SugaredLogger.Errorf("%s, retrying in %s: %s", message, queueRetryDelay, err)
+	select {
+	case <-time.After(queueRetryDelay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// randomId returns a new random id.
+func randomId() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
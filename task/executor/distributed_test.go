@@ -0,0 +1,263 @@
+package executor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"hotalert/task"
+	"hotalert/taskqueue"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestDistributedOptions returns options with a short lease on a new MemoryQueue.
+func newTestDistributedOptions() DistributedOptions {
+	options := DefaultDistributedOptions(taskqueue.NewMemoryQueue())
+	options.Lease = 300 * time.Millisecond
+	return options
+}
+
+// newTestDistributedExecutor returns a started DistributedExecutor.
+func newTestDistributedExecutor(t *testing.T, distributedOptions DistributedOptions) *DistributedExecutor {
+	distributedExecutor, err := NewDistributedExecutor(DefaultOptions(), distributedOptions)
+	assert.NoError(t, err)
+	distributedExecutor.Start()
+	return distributedExecutor
+}
+
+// newTestQueueWorker returns a started QueueWorker.
+func newTestQueueWorker(t *testing.T, distributedOptions DistributedOptions) *QueueWorker {
+	queueWorker, err := NewQueueWorker(NewDefaultExecutor(), distributedOptions)
+	assert.NoError(t, err)
+	queueWorker.Start()
+	return queueWorker
+}
+
+// registerTestFunction registers the function under a random name and returns the name.
+func registerTestFunction(t *testing.T, function ExecutionFunc) string {
+	name, err := randomHex(5)
+	assert.NoError(t, err)
+	assert.NoError(t, RegisterNewExecutionFunction(name, function))
+	return name
+}
+
+func Test_DistributedExecutor(t *testing.T) {
+	alertFunction := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		currentTask.Alerter.PostAlert(ctx, []string{currentTask.Options["keyword"].(string)})
+		task.SetOutput(ctx, "worker", "queue")
+		return nil
+	})
+	var statusCalls int32
+	statusFunction := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		atomic.AddInt32(&statusCalls, 1)
+		return &task.StatusCodeError{Message: "Failed to query website", StatusCode: 503}
+	})
+	panicFunction := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		panic("test")
+	})
+
+	distributedOptions := newTestDistributedOptions()
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+	queueWorker := newTestQueueWorker(t, distributedOptions)
+	defer queueWorker.Shutdown()
+
+	alerter := &recordingAlerter{alerts: make(chan []string, 1)}
+	report := distributedExecutor.SubmitBatch([]*task.Task{
+		{
+			ExecutionFuncName: alertFunction,
+			Options:           task.Options{"keyword": "In stock"},
+			Timeout:           10 * time.Second,
+			Alerter:           alerter,
+		},
+		{
+			ExecutionFuncName: statusFunction,
+			Timeout:           10 * time.Second,
+			Retry:             task.RetryPolicy{Retries: 1, RetryOn: []string{task.RetryOn5xx}},
+			Alerter:           alert.NewDummyAlerter(),
+		},
+		{
+			ExecutionFuncName: panicFunction,
+			Timeout:           10 * time.Second,
+			Alerter:           alert.NewDummyAlerter(),
+		},
+	}).Wait()
+
+	// The results are in the order in which the tasks completed.
+	var results = make(map[string]*task.Result)
+	for _, result := range report.Results {
+		results[result.InitialTask.ExecutionFuncName] = result
+	}
+	assert.NoError(t, results[alertFunction].Error())
+	assert.Equal(t, map[string]string{"worker": "queue"}, results[alertFunction].Outputs())
+	assert.Equal(t, []string{"In stock"}, <-alerter.alerts)
+
+	// The kind of the error is kept, so the task is retried.
+	assert.EqualError(t, results[statusFunction].Error(), "Failed to query website, status code 503")
+	assert.Equal(t, 2, results[statusFunction].Attempts())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&statusCalls))
+
+	assert.EqualError(t, results[panicFunction].Error(), "panic: test")
+}
+
+func Test_DistributedExecutor_CrashedWorker(t *testing.T) {
+	function := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		task.SetOutput(ctx, "done", "yes")
+		return nil
+	})
+	distributedOptions := newTestDistributedOptions()
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+
+	batch := distributedExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: function,
+		Timeout:           10 * time.Second,
+		Alerter:           alert.NewDummyAlerter(),
+	}})
+	// A worker leases the task and crashes without acknowledging it.
+	_, err := distributedOptions.Queue.Pop(context.Background(), distributedOptions.tasksQueue(), distributedOptions.Lease)
+	assert.NoError(t, err)
+
+	queueWorker := newTestQueueWorker(t, distributedOptions)
+	defer queueWorker.Shutdown()
+	report := batch.Wait()
+	assert.NoError(t, report.Results[0].Error())
+	assert.Equal(t, map[string]string{"done": "yes"}, report.Results[0].Outputs())
+}
+
+func Test_DistributedExecutor_MaxDeliveries(t *testing.T) {
+	var calls int32
+	function := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	distributedOptions := newTestDistributedOptions()
+	distributedOptions.MaxDeliveries = 2
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+
+	batch := distributedExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: function,
+		Timeout:           10 * time.Second,
+		Alerter:           alert.NewDummyAlerter(),
+	}})
+	for i := 0; i < 2; i++ {
+		_, err := distributedOptions.Queue.Pop(context.Background(), distributedOptions.tasksQueue(), distributedOptions.Lease)
+		assert.NoError(t, err)
+	}
+
+	queueWorker := newTestQueueWorker(t, distributedOptions)
+	defer queueWorker.Shutdown()
+	report := batch.Wait()
+	assert.EqualError(t, report.Results[0].Error(), "task was delivered 2 times, its workers stopped before completing it")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func Test_DistributedExecutor_Timeout(t *testing.T) {
+	var calls int32
+	function := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	distributedOptions := newTestDistributedOptions()
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+
+	// Without workers the task times out.
+	report := distributedExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: function,
+		Timeout:           200 * time.Millisecond,
+		Alerter:           alert.NewDummyAlerter(),
+	}}).Wait()
+	assert.ErrorIs(t, report.Results[0].Error(), context.DeadlineExceeded)
+
+	// The worker skips the task once the executor stopped waiting for it.
+	queueWorker := newTestQueueWorker(t, distributedOptions)
+	time.Sleep(100 * time.Millisecond)
+	queueWorker.Shutdown()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := distributedOptions.Queue.Pop(ctx, distributedOptions.tasksQueue(), distributedOptions.Lease)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_QueueWorker_ExtendsLease(t *testing.T) {
+	var calls int32
+	function := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Second)
+		return nil
+	})
+	distributedOptions := newTestDistributedOptions()
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+	// Both workers would execute the task if its lease was not renewed.
+	for i := 0; i < 2; i++ {
+		queueWorker := newTestQueueWorker(t, distributedOptions)
+		defer queueWorker.Shutdown()
+	}
+
+	report := distributedExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: function,
+		Timeout:           10 * time.Second,
+		Alerter:           alert.NewDummyAlerter(),
+	}}).Wait()
+	assert.NoError(t, report.Results[0].Error())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_QueueWorker_Shutdown(t *testing.T) {
+	var calls int32
+	function := registerTestFunction(t, func(ctx context.Context, currentTask *task.Task) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	distributedOptions := newTestDistributedOptions()
+	distributedExecutor := newTestDistributedExecutor(t, distributedOptions)
+	defer distributedExecutor.Shutdown()
+
+	batch := distributedExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: function,
+		Timeout:           10 * time.Second,
+		Alerter:           alert.NewDummyAlerter(),
+	}})
+	firstWorker := newTestQueueWorker(t, distributedOptions)
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The task cancelled by the shutdown is left to another worker.
+	firstWorker.Shutdown()
+	secondWorker := newTestQueueWorker(t, distributedOptions)
+	defer secondWorker.Shutdown()
+	report := batch.Wait()
+	assert.NoError(t, report.Results[0].Error())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_DistributedOptions_Validate(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Modify        func(options *DistributedOptions)
+		ExpectedError string
+	}{
+		{"NoQueue", func(options *DistributedOptions) { options.Queue = nil }, "invalid queue, cannot be nil"},
+		{"NoName", func(options *DistributedOptions) { options.Name = "" }, "invalid queue name, cannot be empty"},
+		{"Lease", func(options *DistributedOptions) { options.Lease = 0 }, "invalid lease 0s, must be positive"},
+		{"MaxDeliveries", func(options *DistributedOptions) { options.MaxDeliveries = 0 }, "invalid max deliveries 0, must be at least 1"},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			options := DefaultDistributedOptions(taskqueue.NewMemoryQueue())
+			assert.NoError(t, options.Validate())
+			tv.Modify(&options)
+			assert.EqualError(t, options.Validate(), tv.ExpectedError)
+		})
+	}
+}
@@ -1,26 +1,57 @@
 package executor
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"hotalert/hostlimit"
 	"hotalert/logging"
 	"hotalert/task"
 	"hotalert/task/functions"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Executor is an interface for implementing task executors.
 type Executor interface {
 	// AddTask adds a task to the task executor.
-	AddTask(task *task.Task)
+	// It returns an error if the task was not queued, see Options.FullQueue.
+	AddTask(task *task.Task) error
 	// Start starts the scrapper and returns a Result receive-only channel.
 	Start() <-chan *task.Result
+	// Drain stops accepting tasks and waits until the queued and in-flight tasks are done. When the context is done
+	// first the remaining tasks are cancelled.
+	Drain(ctx context.Context) error
 	// Shutdown shuts down the scrapper. It will block until the Executor was shut down.
 	Shutdown()
 }
 
 // ExecutionFunc is a type definition for a function that executes the task and returns an error.
-type ExecutionFunc func(task *task.Task) error
+// It is the simple form of task.Function, without declared options, see RegisterNewExecutionFunction.
+// The context is cancelled when the task times out or the executor shuts down.
+type ExecutionFunc func(ctx context.Context, task *task.Task) error
+
+// executionFunction adapts an ExecutionFunc to task.Function. Its options are not validated.
+type executionFunction struct {
+	execute ExecutionFunc
+}
+
+func (f executionFunction) Description() string {
+	return ""
+}
+
+func (f executionFunction) OptionNames() []string {
+	return nil
+}
+
+func (f executionFunction) ValidateOptions(options task.Options) error {
+	return nil
+}
+
+func (f executionFunction) Execute(ctx context.Context, task *task.Task) error {
+	return f.execute(ctx, task)
+}
 
 // DefaultExecutor is a TaskExecutor with the default implementation.
 // The tasks are executed directly on the machine.
@@ -31,93 +62,283 @@ type DefaultExecutor struct {
 	numberOfWorkerGoroutines int
 	// taskResultChan is a receive only channel for task results.
 	taskResultChan chan *task.Result
-	// taskChan is a channel for tasks
-	taskChan chan *task.Task
-	// quinChan is a channel for sending the quit command to worker goroutines.
-	quinChan chan int
+	// taskQueue holds the tasks waiting for a worker, by priority and workload.
+	taskQueue *taskQueue
+	// stopping is set when the executor stops accepting tasks, it is guarded by stoppingMutex.
+	stopping bool
+	// stoppingMutex is held for reading while a task is added and for writing while taskQueue is closed.
+	stoppingMutex sync.RWMutex
+	// closeResultsOnce closes taskResultChan once.
+	closeResultsOnce sync.Once
+	// fullQueue is the behavior of AddTask when taskQueue is full.
+	fullQueue string
+	// middleware wraps the task function calls, see Use.
+	middleware []Middleware
+	// functionHandler returns the handler which calls the task function, inside the middleware.
+	functionHandler func(function task.Function) Handler
+	// hostLimiter limits the http requests sent to each host by the tasks.
+	hostLimiter *hostlimit.Limiter
+	// ctx is the parent context of the task contexts, it is cancelled on Shutdown.
+	ctx context.Context
+	// cancel cancels ctx.
+	cancel context.CancelFunc
 }
 
-// executionFuncMap is a map that holds all the possible values for ExecutionFunc.
-// Right now it is hard-coded but in the future it may be extended dynamically.
-var executionFuncMap = map[string]ExecutionFunc{
-	"web_scrape": functions.WebScrapeTask,
+// functionMap is a map that holds all the registered task functions by name.
+var functionMap = map[string]task.Function{
+	"web_scrape":  functions.WebScrape,
+	"tls_expiry":  functions.TlsExpiry,
+	"tcp_check":   functions.TcpCheck,
+	"dns_check":   functions.DnsCheck,
+	"rss_watch":   functions.RssWatch,
+	"exec":        functions.Exec,
+	"file_watch":  functions.FileWatch,
+	"http_flow":   functions.HttpFlow,
+	"value_track": functions.ValueTrack,
 }
 
-// RegisterNewExecutionFunction registers a new execution function.
-func RegisterNewExecutionFunction(name string, function ExecutionFunc) error {
-	for n := range executionFuncMap {
-		if n == name {
-			return errors.New("function already exists")
-		}
+// RegisterFunction registers a new task function.
+func RegisterFunction(name string, function task.Function) error {
+	if _, ok := functionMap[name]; ok {
+		return errors.New("function already exists")
 	}
-	executionFuncMap[name] = function
+	functionMap[name] = function
 	return nil
 }
 
-// NewDefaultExecutor returns a new instance of DefaultExecutor.
+// RegisterNewExecutionFunction registers a new execution function, which does not declare its options.
+func RegisterNewExecutionFunction(name string, function ExecutionFunc) error {
+	return RegisterFunction(name, executionFunction{execute: function})
+}
+
+// LookupFunction returns the task function registered under the given name.
+func LookupFunction(name string) (task.Function, bool) {
+	function, ok := functionMap[name]
+	return function, ok
+}
+
+// FunctionNames returns the sorted names of the registered task functions.
+func FunctionNames() []string {
+	var names = make([]string, 0, len(functionMap))
+	for name := range functionMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateTask returns an error if the task function of the task does not exist or if the task options are invalid.
+func ValidateTask(task *task.Task) error {
+	function, ok := functionMap[task.ExecutionFuncName]
+	if !ok {
+		return errors.New(fmt.Sprintf("invalid task execution function name: '%s'", task.ExecutionFuncName))
+	}
+	return function.ValidateOptions(task.Options)
+}
+
+// NewDefaultExecutor returns a new instance of DefaultExecutor with the default options.
 func NewDefaultExecutor() *DefaultExecutor {
+	ws, _ := NewDefaultExecutorWithOptions(DefaultOptions())
+	return ws
+}
+
+// NewDefaultExecutorWithOptions returns a new instance of DefaultExecutor given its options.
+// It returns an error if the options are invalid.
+func NewDefaultExecutorWithOptions(options Options) (*DefaultExecutor, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
 	ws := &DefaultExecutor{
 		workerGroup:              &sync.WaitGroup{},
-		taskResultChan:           make(chan *task.Result, 50),
-		taskChan:                 make(chan *task.Task, 50),
-		numberOfWorkerGoroutines: 5,
+		taskResultChan:           make(chan *task.Result, options.QueueSize),
+		taskQueue:                newTaskQueue(options.QueueSize, options.Workers),
+		numberOfWorkerGoroutines: options.Workers,
+		fullQueue:                options.FullQueue,
+		functionHandler:          recoverPanic,
+		hostLimiter: hostlimit.New(hostlimit.Limits{
+			Concurrency: options.HostConcurrency,
+			Delay:       options.HostDelay,
+		}, options.Hosts),
 	}
-	ws.quinChan = make(chan int, ws.numberOfWorkerGoroutines)
-	return ws
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+	return ws, nil
 }
 
 // AddTask adds a task to the DefaultExecutor queue.
-func (ws *DefaultExecutor) AddTask(task *task.Task) {
-	ws.taskChan <- task
+// Tasks with a higher priority are executed first, tasks of the same priority are taken in turns from each workload.
+// When the queue is full AddTask blocks, drops the task or returns ErrQueueFull, depending on the full queue option.
+// It returns ErrExecutorStopped once the executor is drained or shut down.
+func (ws *DefaultExecutor) AddTask(task *task.Task) error {
+	return ws.queueTask(queuedTask{task: task})
+}
+
+// queueTask adds a task to the DefaultExecutor queue, see AddTask.
+func (ws *DefaultExecutor) queueTask(queued queuedTask) error {
+	ws.stoppingMutex.RLock()
+	defer ws.stoppingMutex.RUnlock()
+	if ws.stopping {
+		return ErrExecutorStopped
+	}
+	if ws.fullQueue == FullQueueBlock {
+		if !ws.taskQueue.push(ws.ctx, queued) {
+			return ErrExecutorStopped
+		}
+		return nil
+	}
+	if ws.taskQueue.tryPush(queued) {
+		return nil
+	}
+	if ws.fullQueue == FullQueueDrop {
+		logging.SugaredLogger.Warnf("Task queue is full, dropping task %s", queued.task.ExecutionFuncName)
+		return ErrTaskDropped
+	}
+	return ErrQueueFull
 }
 
-// executeTask executes the given task using DefaultTaskExecutionFuncName
-func (ws *DefaultExecutor) executeTask(task *task.Task) error {
-	var taskErr error = nil
-	// Execute task and set panics as errors in taskResult.
-	func() {
+// executeTask executes the given task with its task function.
+// The function receives a context which is cancelled when the task timeout elapses or the executor shuts down.
+// The timeout is enforced even if the function ignores the context: executeTask returns a timeout error and the
+// function is left to finish in the background.
+// The function sets its outputs in the given outputs.
+func (ws *DefaultExecutor) executeTask(currentTask *task.Task, outputs *task.Outputs) error {
+	if err := ws.ctx.Err(); err != nil {
+		return fmt.Errorf("task cancelled: %w", err)
+	}
+	taskFunction, ok := functionMap[currentTask.ExecutionFuncName]
+	if !ok {
+		message := fmt.Sprintf("invalid task execution function name: '%s'", currentTask.ExecutionFuncName)
+		logging.SugaredLogger.Error(message)
+		return errors.New(message)
+	}
+
+	ctx, cancel := taskContext(ws.ctx, currentTask)
+	defer cancel()
+	ctx = hostlimit.NewContext(ctx, ws.hostLimiter)
+	ctx = task.ContextWithOutputs(ctx, outputs)
+
+	// Execute task through the middleware and set panics as errors in taskResult, including panics of the middleware.
+	handler := chainMiddleware(ws.functionHandler(taskFunction), ws.middleware)
+	var done = make(chan error, 1)
+	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				taskErr = errors.New(fmt.Sprintf("panic: %s", r))
+				done <- &PanicError{Value: r}
 			}
 		}()
+		done <- handler(ctx, currentTask)
+	}()
 
-		taskExecutionFunc, ok := executionFuncMap[task.ExecutionFuncName]
-		if !ok {
-			message := fmt.Sprintf("invalid task execution function name: '%s'", task.ExecutionFuncName)
-			logging.SugaredLogger.Error(message)
-			taskErr = errors.New(message)
-			return
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Prefer the result of the function if it finished at the same time.
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("task timed out after %s: %w", currentTask.Timeout, ctx.Err())
 		}
+		return fmt.Errorf("task cancelled: %w", ctx.Err())
+	}
+}
 
-		err := taskExecutionFunc(task)
-		if err != nil {
-			taskErr = err
+// executeTaskWithRetries executes the task and retries it according to its retry policy, waiting for the backoff
+// between attempts. It returns the number of attempts and the outputs and error of the last attempt.
+// Retries stop when the executor shuts down.
+func (ws *DefaultExecutor) executeTaskWithRetries(currentTask *task.Task) (int, map[string]string, error) {
+	var attempt = 1
+	for {
+		outputs := task.NewOutputs()
+		err := ws.executeTask(currentTask, outputs)
+		if !currentTask.Retry.ShouldRetry(attempt, err) {
+			return attempt, outputs.Values(), err
 		}
-	}()
-	return taskErr
+		backoff := currentTask.Retry.BackoffBefore(attempt)
+		logging.SugaredLogger.Warnf("Task %s failed, retrying in %s: %s", currentTask.ExecutionFuncName, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ws.ctx.Done():
+			return attempt, outputs.Values(), err
+		}
+		attempt += 1
+	}
+}
+
+// taskContext returns the context of the task, derived from parent, which times out after the task timeout.
+// Tasks without a positive timeout only end when parent is cancelled.
+func taskContext(parent context.Context, currentTask *task.Task) (context.Context, context.CancelFunc) {
+	if currentTask.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, currentTask.Timeout)
 }
 
-// workerGoroutine waits for tasks and executes them.
+// workerGoroutine waits for tasks and executes them until the task queue is closed and empty.
 // After the task is executed it forwards the result, including errors and panics to the task Result channel.
 func (ws *DefaultExecutor) workerGoroutine() {
 	defer ws.workerGroup.Done()
 	for {
-		select {
-		case currentTask := <-ws.taskChan:
-			var taskResult = task.NewResult(currentTask)
-			err := ws.executeTask(currentTask)
-			taskResult.SetError(err)
-
-			// Forward TaskResult to channel.
-			ws.taskResultChan <- taskResult
-		case <-ws.quinChan:
-			// Quit
+		queued, ok := ws.taskQueue.pop()
+		if !ok {
 			return
 		}
+		var taskResult = task.NewResult(queued.task)
+		attempts, outputs, err := ws.executeTaskWithRetries(queued.executedTask())
+		taskResult.SetAttempts(attempts)
+		taskResult.SetOutputs(outputs)
+		taskResult.SetError(err)
+
+		ws.completeTask(queued, taskResult)
+	}
+}
+
+// completeTask calls the callback of the task and forwards the result to the batch of the task or to the task Result
+// channel.
+func (ws *DefaultExecutor) completeTask(queued queuedTask, taskResult *task.Result) {
+	runCallback(queued.task, taskResult)
+	if queued.batch != nil {
+		queued.batch.addResult(taskResult)
+	} else {
+		ws.forwardResult(taskResult)
+	}
+}
+
+// runCallback calls the callback of the task, if any, with its result. Panics of the callback are logged.
+func runCallback(currentTask *task.Task, taskResult *task.Result) {
+	if currentTask.Callback == nil || *currentTask.Callback == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logging.SugaredLogger.Errorf("Callback of task %s panicked: %s", currentTask.ExecutionFuncName, r)
+		}
+	}()
+	(*currentTask.Callback)(taskResult)
+}
+
+// forwardResult sends the result to the task Result channel.
+// Once the tasks are cancelled the result is dropped if nobody reads the channel, so that shutting down never blocks.
+func (ws *DefaultExecutor) forwardResult(taskResult *task.Result) {
+	select {
+	case ws.taskResultChan <- taskResult:
+	case <-ws.ctx.Done():
+		select {
+		case ws.taskResultChan <- taskResult:
+		default:
+			logging.SugaredLogger.Warnf("Dropping result of cancelled task %s", taskResult.InitialTask.ExecutionFuncName)
+		}
 	}
 }
 
+// Use adds middleware which wraps each task function call, the first middleware added is the outermost one.
+// Use must be called before Start.
+func (ws *DefaultExecutor) Use(middleware ...Middleware) {
+	ws.middleware = append(ws.middleware, middleware...)
+}
+
 // Start starts the DefaultExecutor.
 // Start returns a receive only channel with task Result.
 func (ws *DefaultExecutor) Start() <-chan *task.Result {
@@ -129,15 +350,57 @@ func (ws *DefaultExecutor) Start() <-chan *task.Result {
 	return ws.taskResultChan
 }
 
+// stopAccepting makes AddTask reject new tasks and closes the task queue, so the workers quit once it is empty.
+func (ws *DefaultExecutor) stopAccepting() {
+	// The blocked AddTask calls hold stoppingMutex until they get room, wake them up first.
+	ws.taskQueue.stopPushing()
+	ws.stoppingMutex.Lock()
+	defer ws.stoppingMutex.Unlock()
+	if !ws.stopping {
+		ws.stopping = true
+		ws.taskQueue.close()
+	}
+}
+
+// Drain stops accepting tasks and waits until the queued and in-flight tasks are done and their results forwarded.
+// If the context is done first, the remaining tasks are cancelled, Drain waits for the workers to quit and returns
+// the context error. The results of cancelled tasks are forwarded as well, with a cancellation error.
+func (ws *DefaultExecutor) Drain(ctx context.Context) error {
+	ws.stopAccepting()
+	var done = make(chan struct{})
+	go func() {
+		ws.workerGroup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		ws.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
 // Shutdown shuts down the DefaultExecutor.
-// Shutdown blocks till the DefaultExecutor has shutdown.
+// The tasks in progress and the queued tasks are cancelled, use Drain before Shutdown to let them finish.
+// Shutdown blocks till the DefaultExecutor has shutdown, then it closes the task Result channel.
 func (ws *DefaultExecutor) Shutdown() {
-	// Shutdown all worker goroutines
-	for i := 0; i < ws.numberOfWorkerGoroutines; i++ {
-		ws.quinChan <- 1
-	}
+	ws.cancel()
+	ws.stopAccepting()
 	ws.workerGroup.Wait()
-	close(ws.taskChan)
-	close(ws.taskResultChan)
-	close(ws.quinChan)
+
+	// Tasks are left in the queue when the executor was not started, they complete without being executed.
+	for {
+		queued, ok := ws.taskQueue.pop()
+		if !ok {
+			break
+		}
+		var taskResult = task.NewResult(queued.task)
+		taskResult.SetError(ErrExecutorStopped)
+		ws.completeTask(queued, taskResult)
+	}
+	ws.closeResultsOnce.Do(func() {
+		close(ws.taskResultChan)
+	})
 }
@@ -1,13 +1,19 @@
 package executor
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"hotalert/alert"
+	"hotalert/hostlimit"
 	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 )
 
 func randomHex(n int) (string, error) {
@@ -20,8 +26,11 @@ func randomHex(n int) (string, error) {
 
 func Test_DefaultExecutor(t *testing.T) {
 	// Setup
+	var taskCounterMutex sync.Mutex
 	var taskCounter = 0
-	var taskTestFunc = func(task *task.Task) error {
+	var taskTestFunc = func(ctx context.Context, task *task.Task) error {
+		taskCounterMutex.Lock()
+		defer taskCounterMutex.Unlock()
 		// First task is successful, others return error.
 		if taskCounter > 0 {
 			return errors.New("test")
@@ -33,7 +42,9 @@ func Test_DefaultExecutor(t *testing.T) {
 	err := RegisterNewExecutionFunction("task_test", taskTestFunc)
 	assert.NoError(t, err)
 
-	defaultExecutor := NewDefaultExecutor()
+	// A single worker executes the tasks in order.
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 50, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
 	taskResultsChan := defaultExecutor.Start()
 
 	// Test
@@ -88,11 +99,375 @@ func Test_DefaultExecutor_InvalidTaskExecutionFuncName(t *testing.T) {
 	defaultExecutor.Shutdown()
 }
 
+func Test_DefaultExecutor_Timeout(t *testing.T) {
+	var deadlineChan = make(chan bool, 1)
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, task *task.Task) error {
+		_, hasDeadline := ctx.Deadline()
+		deadlineChan <- hasDeadline
+		// Ignore the context, the executor enforces the timeout anyway.
+		time.Sleep(2 * time.Second)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor := NewDefaultExecutor()
+	taskResultsChan := defaultExecutor.Start()
+	defer defaultExecutor.Shutdown()
+
+	start := time.Now()
+	defaultExecutor.AddTask(&task.Task{
+		ExecutionFuncName: functionName,
+		Timeout:           50 * time.Millisecond,
+		Alerter:           alert.NewDummyAlerter(),
+	})
+	result := <-taskResultsChan
+	assert.True(t, <-deadlineChan)
+	assert.ErrorIs(t, result.Error(), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func Test_DefaultExecutor_Panic(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, task *task.Task) error {
+		panic("test")
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor := NewDefaultExecutor()
+	taskResultsChan := defaultExecutor.Start()
+	defer defaultExecutor.Shutdown()
+
+	defaultExecutor.AddTask(&task.Task{
+		ExecutionFuncName: functionName,
+		Alerter:           alert.NewDummyAlerter(),
+	})
+	result := <-taskResultsChan
+	assert.EqualError(t, result.Error(), "panic: test")
+}
+
+func Test_DefaultExecutor_ShutdownCancelsTasks(t *testing.T) {
+	var startedChan = make(chan bool, 1)
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, task *task.Task) error {
+		startedChan <- true
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor := NewDefaultExecutor()
+	taskResultsChan := defaultExecutor.Start()
+	defaultExecutor.AddTask(&task.Task{
+		ExecutionFuncName: functionName,
+		Alerter:           alert.NewDummyAlerter(),
+	})
+	<-startedChan
+	defaultExecutor.Shutdown()
+
+	result := <-taskResultsChan
+	assert.ErrorIs(t, result.Error(), context.Canceled)
+}
+
 func Test_RegisterNewExecutionFunction(t *testing.T) {
-	var taskTestFunc = func(t *task.Task) error { return nil }
+	var taskTestFunc = func(ctx context.Context, t *task.Task) error { return nil }
 	randomName, _ := randomHex(5)
 	err := RegisterNewExecutionFunction(randomName, taskTestFunc)
 	assert.NoError(t, err)
 	err = RegisterNewExecutionFunction(randomName, taskTestFunc)
 	assert.Error(t, err)
 }
+
+func Test_RegisterFunction(t *testing.T) {
+	randomName, _ := randomHex(5)
+	function, ok := LookupFunction("web_scrape")
+	assert.True(t, ok)
+	assert.NoError(t, RegisterFunction(randomName, function))
+	assert.Error(t, RegisterFunction(randomName, function))
+	assert.Contains(t, FunctionNames(), randomName)
+}
+
+func Test_FunctionNames(t *testing.T) {
+	names := FunctionNames()
+	assert.IsNonDecreasing(t, names)
+	for _, name := range []string{"web_scrape", "tls_expiry", "tcp_check", "dns_check", "rss_watch", "exec", "file_watch", "http_flow", "value_track"} {
+		assert.Contains(t, names, name)
+		function, _ := LookupFunction(name)
+		assert.NotEmpty(t, function.Description())
+		assert.NotEmpty(t, function.OptionNames())
+	}
+}
+
+func Test_ValidateTask(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Task          *task.Task
+		ExpectedError bool
+	}{
+		{"Valid", task.NewTask("web_scrape", task.Options{"url": "https://example.com", "keywords": []any{"a"}}, alert.NewDummyAlerter()), false},
+		{"UnknownFunction", task.NewTask("vand_dacia_2006", task.Options{}, alert.NewDummyAlerter()), true},
+		{"UnknownOption", task.NewTask("web_scrape", task.Options{"url": "https://example.com", "keywrods": []any{"a"}}, alert.NewDummyAlerter()), true},
+		{"MissingOption", task.NewTask("tcp_check", task.Options{}, alert.NewDummyAlerter()), true},
+		{"InvalidStep", task.NewTask("http_flow", task.Options{"steps": []any{map[string]any{"url": "https://example.com", "methd": "POST"}}, "keywords": []any{"a"}}, alert.NewDummyAlerter()), true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			err := ValidateTask(tv.Task)
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_NewDefaultExecutorWithOptions_InvalidOptions(t *testing.T) {
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 0, QueueSize: 1, FullQueue: FullQueueBlock})
+	assert.Nil(t, defaultExecutor)
+	assert.Error(t, err)
+}
+
+func Test_DefaultExecutor_FullQueue(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		FullQueue     string
+		ExpectedError error
+	}{
+		{"Drop", FullQueueDrop, ErrTaskDropped},
+		{"Error", FullQueueError, ErrQueueFull},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			// The executor is not started, so the queue fills up after the first task.
+			defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 1, FullQueue: tv.FullQueue})
+			assert.NoError(t, err)
+			var currentTask = &task.Task{ExecutionFuncName: "task_test", Alerter: alert.NewDummyAlerter()}
+
+			assert.NoError(t, defaultExecutor.AddTask(currentTask))
+			assert.Equal(t, tv.ExpectedError, defaultExecutor.AddTask(currentTask))
+
+			defaultExecutor.Shutdown()
+		})
+	}
+}
+
+func Test_DefaultExecutor_Workers(t *testing.T) {
+	// All workers must run concurrently for the tasks to finish.
+	const workers = 3
+	var started = make(chan struct{})
+	var release = make(chan struct{})
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, task *task.Task) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: workers, QueueSize: 0, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
+	taskResultsChan := defaultExecutor.Start()
+
+	go func() {
+		for i := 0; i < workers; i++ {
+			_ = defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()})
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the tasks were not executed concurrently")
+		}
+	}
+	close(release)
+	for i := 0; i < workers; i++ {
+		assert.NoError(t, (<-taskResultsChan).Error())
+	}
+
+	defaultExecutor.Shutdown()
+}
+
+func Test_DefaultExecutor_Retry(t *testing.T) {
+	var tests = []struct {
+		TestName         string
+		RetryPolicy      task.RetryPolicy
+		ExpectedAttempts int
+		ExpectedError    bool
+	}{
+		{"NoRetries", task.RetryPolicy{}, 1, true},
+		{"SucceedsOnRetry", task.RetryPolicy{Retries: 3, Backoff: time.Millisecond}, 3, false},
+		{"RetriesExhausted", task.RetryPolicy{Retries: 1, Backoff: time.Millisecond}, 2, true},
+		{"RetryOnDoesNotMatch", task.RetryPolicy{Retries: 3, RetryOn: []string{task.RetryOnTimeout}}, 1, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			// The function fails twice with a server error, then succeeds.
+			var calls = 0
+			functionName, _ := randomHex(5)
+			err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+				calls += 1
+				if calls <= 2 {
+					return &task.StatusCodeError{Message: "Failed to query website", StatusCode: 503}
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+
+			defaultExecutor := NewDefaultExecutor()
+			taskResultsChan := defaultExecutor.Start()
+			_ = defaultExecutor.AddTask(&task.Task{
+				ExecutionFuncName: functionName,
+				Retry:             tv.RetryPolicy,
+				Alerter:           alert.NewDummyAlerter(),
+			})
+
+			result := <-taskResultsChan
+			assert.Equal(t, tv.ExpectedAttempts, result.Attempts())
+			assert.Equal(t, tv.ExpectedError, result.Error() != nil)
+
+			defaultExecutor.Shutdown()
+		})
+	}
+}
+
+func Test_DefaultExecutor_HostLimits(t *testing.T) {
+	var mutex sync.Mutex
+	var inFlight, maxInFlight int
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		inFlight += 1
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		inFlight -= 1
+		mutex.Unlock()
+	}))
+	defer testServer.Close()
+
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, testServer.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := hostlimit.Client(http.DefaultClient).Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	assert.NoError(t, err)
+
+	options := DefaultOptions()
+	options.HostConcurrency = 1
+	defaultExecutor, err := NewDefaultExecutorWithOptions(options)
+	assert.NoError(t, err)
+	taskResultsChan := defaultExecutor.Start()
+
+	// The workers run the tasks concurrently but the requests to the host are sent one at a time.
+	for i := 0; i < options.Workers; i++ {
+		_ = defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Timeout: 10 * time.Second, Alerter: alert.NewDummyAlerter()})
+	}
+	for i := 0; i < options.Workers; i++ {
+		assert.NoError(t, (<-taskResultsChan).Error())
+	}
+	assert.Equal(t, 1, maxInFlight)
+
+	defaultExecutor.Shutdown()
+}
+
+func Test_DefaultExecutor_Drain(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 10, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
+	taskResultsChan := defaultExecutor.Start()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()}))
+	}
+
+	// The queued tasks are executed before Drain returns, new tasks are rejected.
+	assert.NoError(t, defaultExecutor.Drain(context.Background()))
+	assert.Equal(t, ErrExecutorStopped, defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName}))
+	defaultExecutor.Shutdown()
+
+	var results = 0
+	for result := range taskResultsChan {
+		assert.NoError(t, result.Error())
+		results += 1
+	}
+	assert.Equal(t, 3, results)
+}
+
+func Test_DefaultExecutor_DrainBlockedAddTask(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 1, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
+	defaultExecutor.Start()
+	defer defaultExecutor.Shutdown()
+	// The worker executes the first task and the second one fills the queue.
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()}))
+	}
+	var addErr = make(chan error, 1)
+	go func() {
+		addErr <- defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The blocked AddTask call does not delay Drain past its grace period.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, defaultExecutor.Drain(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, ErrExecutorStopped, <-addErr)
+}
+
+func Test_DefaultExecutor_DrainGracePeriod(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, currentTask *task.Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	defaultExecutor, err := NewDefaultExecutorWithOptions(Options{Workers: 1, QueueSize: 10, FullQueue: FullQueueBlock})
+	assert.NoError(t, err)
+	taskResultsChan := defaultExecutor.Start()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, defaultExecutor.AddTask(&task.Task{ExecutionFuncName: functionName, Alerter: alert.NewDummyAlerter()}))
+	}
+
+	// The tasks do not finish in the grace period, so the in-flight and the queued task are cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, defaultExecutor.Drain(ctx), context.DeadlineExceeded)
+	defaultExecutor.Shutdown()
+
+	var results = 0
+	for result := range taskResultsChan {
+		assert.ErrorIs(t, result.Error(), context.Canceled)
+		results += 1
+	}
+	assert.Equal(t, 2, results)
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/task"
+)
+
+// Handler executes a task, it is the task function call wrapped by the middleware of the executor.
+type Handler func(ctx context.Context, task *task.Task) error
+
+// Middleware wraps the Handler which executes a task, for example to log, measure or trace the executions.
+// A middleware may run code before and after calling next, change the returned error, or not call next at all.
+// Each attempt of a retried task goes through the middleware.
+type Middleware func(next Handler) Handler
+
+// PanicError is the error of a task whose function panicked.
+type PanicError struct {
+	// Value is the value given to panic.
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %s", e.Value)
+}
+
+// Hooks are functions called around each task execution, all of them are optional.
+// The hooks are called by the worker which executes the task and must not block.
+type Hooks struct {
+	// Before is called before the task function.
+	Before func(ctx context.Context, task *task.Task)
+	// After is called after the task function with its error, which is nil if it succeeded.
+	After func(ctx context.Context, task *task.Task, err error)
+	// OnError is called after the task function when it failed, before After.
+	OnError func(ctx context.Context, task *task.Task, err error)
+	// OnPanic is called after the task function when it panicked, with the value given to panic, before OnError.
+	OnPanic func(ctx context.Context, task *task.Task, value any)
+}
+
+// Middleware returns the middleware which calls the hooks.
+func (h Hooks) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, currentTask *task.Task) error {
+			if h.Before != nil {
+				h.Before(ctx, currentTask)
+			}
+			err := next(ctx, currentTask)
+			var panicErr *PanicError
+			if h.OnPanic != nil && errors.As(err, &panicErr) {
+				h.OnPanic(ctx, currentTask, panicErr.Value)
+			}
+			if h.OnError != nil && err != nil {
+				h.OnError(ctx, currentTask, err)
+			}
+			if h.After != nil {
+				h.After(ctx, currentTask, err)
+			}
+			return err
+		}
+	}
+}
+
+// chainMiddleware returns the handler wrapped by the middleware, the first middleware is the outermost one.
+func chainMiddleware(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// recoverPanic returns a handler which returns a PanicError when the task function panics, so that the middleware
+// sees panics as errors.
+func recoverPanic(function task.Function) Handler {
+	return func(ctx context.Context, currentTask *task.Task) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r}
+			}
+		}()
+		return function.Execute(ctx, currentTask)
+	}
+}
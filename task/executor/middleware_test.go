@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"hotalert/task"
+	"testing"
+)
+
+func Test_chainMiddleware(t *testing.T) {
+	var calls []string
+	recordingMiddleware := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, currentTask *task.Task) error {
+				calls = append(calls, "before "+name)
+				err := next(ctx, currentTask)
+				calls = append(calls, "after "+name)
+				return err
+			}
+		}
+	}
+	handler := chainMiddleware(func(ctx context.Context, currentTask *task.Task) error {
+		calls = append(calls, "function")
+		return nil
+	}, []Middleware{recordingMiddleware("first"), recordingMiddleware("second")})
+
+	assert.NoError(t, handler(context.Background(), &task.Task{}))
+	assert.Equal(t, []string{"before first", "before second", "function", "after second", "after first"}, calls)
+}
+
+func Test_Hooks(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Function      Handler
+		ExpectedCalls []string
+	}{
+		{
+			"Success",
+			func(ctx context.Context, currentTask *task.Task) error { return nil },
+			[]string{"before", "after <nil>"},
+		},
+		{
+			"Error",
+			func(ctx context.Context, currentTask *task.Task) error { return errors.New("test") },
+			[]string{"before", "error test", "after test"},
+		},
+		{
+			"Panic",
+			func(ctx context.Context, currentTask *task.Task) error { return &PanicError{Value: "test"} },
+			[]string{"before", "panic test", "error panic: test", "after panic: test"},
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			var calls []string
+			hooks := Hooks{
+				Before: func(ctx context.Context, currentTask *task.Task) {
+					calls = append(calls, "before")
+				},
+				After: func(ctx context.Context, currentTask *task.Task, err error) {
+					calls = append(calls, fmt.Sprintf("after %v", err))
+				},
+				OnError: func(ctx context.Context, currentTask *task.Task, err error) {
+					calls = append(calls, fmt.Sprintf("error %s", err))
+				},
+				OnPanic: func(ctx context.Context, currentTask *task.Task, value any) {
+					calls = append(calls, fmt.Sprintf("panic %s", value))
+				},
+			}
+			_ = hooks.Middleware()(tv.Function)(context.Background(), &task.Task{})
+			assert.Equal(t, tv.ExpectedCalls, calls)
+		})
+	}
+}
+
+func Test_DefaultExecutor_Middleware(t *testing.T) {
+	functionName, _ := randomHex(5)
+	err := RegisterNewExecutionFunction(functionName, func(ctx context.Context, task *task.Task) error {
+		panic("test")
+	})
+	assert.NoError(t, err)
+
+	var panicValues = make(chan any, 1)
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Use(Hooks{
+		OnPanic: func(ctx context.Context, currentTask *task.Task, value any) {
+			panicValues <- value
+		},
+	}.Middleware())
+	taskResultsChan := defaultExecutor.Start()
+	defer defaultExecutor.Shutdown()
+
+	var callbackResults = make(chan *task.Result, 1)
+	var callback task.Callback = func(result *task.Result) {
+		callbackResults <- result
+	}
+	assert.NoError(t, defaultExecutor.AddTask(&task.Task{
+		ExecutionFuncName: functionName,
+		Alerter:           alert.NewDummyAlerter(),
+		Callback:          &callback,
+	}))
+
+	result := <-taskResultsChan
+	assert.EqualError(t, result.Error(), "panic: test")
+	var panicErr *PanicError
+	assert.ErrorAs(t, result.Error(), &panicErr)
+	assert.Equal(t, "test", <-panicValues)
+	assert.Same(t, result, <-callbackResults)
+}
+
+func Test_DefaultExecutor_CallbackOfSkippedTask(t *testing.T) {
+	var callbackResults = make(chan *task.Result, 2)
+	var callback task.Callback = func(result *task.Result) {
+		callbackResults <- result
+	}
+
+	defaultExecutor := NewDefaultExecutor()
+	defaultExecutor.Start()
+	report := defaultExecutor.SubmitBatch([]*task.Task{
+		{Id: "a", DependsOn: []string{"b"}, Callback: &callback},
+		{Id: "b", DependsOn: []string{"a"}, Callback: &callback},
+	}).Wait()
+	defaultExecutor.Shutdown()
+
+	assert.Len(t, report.Failed(), 2)
+	assert.Error(t, (<-callbackResults).Error())
+	assert.Error(t, (<-callbackResults).Error())
+}
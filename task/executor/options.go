@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"hotalert/hostlimit"
+	"time"
+)
+
+const (
+	// FullQueueBlock makes AddTask wait until the task queue has room.
+	FullQueueBlock = "block"
+	// FullQueueDrop makes AddTask drop the task, log a warning and return ErrTaskDropped.
+	FullQueueDrop = "drop"
+	// FullQueueError makes AddTask return ErrQueueFull without queueing the task.
+	FullQueueError = "error"
+)
+
+// ErrTaskDropped is returned by AddTask when the task queue is full and the task was dropped.
+var ErrTaskDropped = errors.New("task queue is full, the task was dropped")
+
+// ErrQueueFull is returned by AddTask when the task queue is full.
+var ErrQueueFull = errors.New("task queue is full")
+
+// ErrExecutorStopped is returned by AddTask when the executor no longer accepts tasks.
+var ErrExecutorStopped = errors.New("executor is stopped")
+
+// Options are the options for building a DefaultExecutor.
+type Options struct {
+	// Workers is the number of tasks executed concurrently.
+	Workers int `mapstructure:"workers"`
+	// QueueSize is the capacity of the task queue and of the result queue.
+	QueueSize int `mapstructure:"queue_size"`
+	// FullQueue is the behavior of AddTask when the task queue is full, one of FullQueueBlock, FullQueueDrop or
+	// FullQueueError.
+	FullQueue string `mapstructure:"full_queue"`
+	// HostConcurrency is the maximum number of concurrent http requests to a host, zero means no limit.
+	HostConcurrency int `mapstructure:"host_concurrency"`
+	// HostDelay is the minimum delay between the start of two http requests to a host.
+	HostDelay time.Duration `mapstructure:"host_delay"`
+	// Hosts holds the limits of specific hosts, which are used instead of HostConcurrency and HostDelay.
+	Hosts map[string]hostlimit.Limits `mapstructure:"hosts"`
+}
+
+// DefaultOptions returns the options used when the workload has no executor section.
+func DefaultOptions() Options {
+	return Options{
+		Workers:   5,
+		QueueSize: 50,
+		FullQueue: FullQueueBlock,
+	}
+}
+
+// OptionsFromMap builds Options from the executor section of a workload. Missing keys keep the default value.
+func OptionsFromMap(data map[string]any) (Options, error) {
+	options := DefaultOptions()
+	for key, value := range data {
+		var ok bool
+		switch key {
+		case "workers":
+			options.Workers, ok = value.(int)
+		case "queue_size":
+			options.QueueSize, ok = value.(int)
+		case "full_queue":
+			options.FullQueue, ok = value.(string)
+		case "host_concurrency":
+			options.HostConcurrency, ok = value.(int)
+		case "host_delay":
+			options.HostDelay, ok = Seconds(value)
+		case "hosts":
+			var hostsMap map[string]any
+			hostsMap, ok = value.(map[string]any)
+			if ok {
+				options.Hosts = make(map[string]hostlimit.Limits, len(hostsMap))
+			}
+			for host, limitsRaw := range hostsMap {
+				var err error
+				options.Hosts[host], err = limitsFromMap(limitsRaw)
+				if err != nil {
+					return options, errors.New(fmt.Sprintf("invalid limits for host '%s': %s", host, err))
+				}
+			}
+		default:
+			return options, errors.New(fmt.Sprintf("unknown executor option '%s'", key))
+		}
+		if !ok {
+			return options, errors.New(fmt.Sprintf("invalid value for executor option '%s': %v", key, value))
+		}
+	}
+	return options, options.Validate()
+}
+
+// limitsFromMap builds the limits of a host from the hosts section of the executor options.
+// The delay is given in seconds.
+func limitsFromMap(data any) (hostlimit.Limits, error) {
+	var limits hostlimit.Limits
+	limitsMap, ok := data.(map[string]any)
+	if !ok {
+		return limits, errors.New("not a map type")
+	}
+	for key, value := range limitsMap {
+		var ok bool
+		switch key {
+		case "concurrency":
+			limits.Concurrency, ok = value.(int)
+		case "delay":
+			limits.Delay, ok = Seconds(value)
+		default:
+			return limits, errors.New(fmt.Sprintf("unknown option '%s'", key))
+		}
+		if !ok {
+			return limits, errors.New(fmt.Sprintf("invalid value for option '%s': %v", key, value))
+		}
+	}
+	return limits, nil
+}
+
+// Seconds converts a number of seconds decoded from yaml, which may have a fraction, to a duration.
+// It returns false if the value is not a number.
+func Seconds(value any) (time.Duration, bool) {
+	switch number := value.(type) {
+	case int:
+		return time.Duration(number) * time.Second, true
+	case float64:
+		return time.Duration(number * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// Validate validates the Options, returns an error on invalid options.
+func (o *Options) Validate() error {
+	if o.Workers < 1 {
+		return errors.New(fmt.Sprintf("invalid number of workers %d, at least one worker is required", o.Workers))
+	}
+	if o.QueueSize < 0 {
+		return errors.New(fmt.Sprintf("invalid queue size %d, cannot be negative", o.QueueSize))
+	}
+	if o.HostConcurrency < 0 || o.HostDelay < 0 {
+		return errors.New("host limits cannot be negative")
+	}
+	for host, limits := range o.Hosts {
+		if limits.Concurrency < 0 || limits.Delay < 0 {
+			return errors.New(fmt.Sprintf("limits of host '%s' cannot be negative", host))
+		}
+	}
+	switch o.FullQueue {
+	case FullQueueBlock, FullQueueDrop, FullQueueError:
+	default:
+		return errors.New(fmt.Sprintf("invalid full queue behavior '%s', expected block, drop or error", o.FullQueue))
+	}
+	return nil
+}
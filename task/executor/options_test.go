@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/hostlimit"
+	"testing"
+	"time"
+)
+
+func Test_OptionsFromMap(t *testing.T) {
+	options, err := OptionsFromMap(map[string]any{
+		"workers":          2,
+		"full_queue":       "drop",
+		"host_concurrency": 2,
+		"host_delay":       0.5,
+		"hosts": map[string]any{
+			"shop.example.com": map[string]any{"concurrency": 1, "delay": 3},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Options{
+		Workers:         2,
+		QueueSize:       50,
+		FullQueue:       FullQueueDrop,
+		HostConcurrency: 2,
+		HostDelay:       500 * time.Millisecond,
+		Hosts: map[string]hostlimit.Limits{
+			"shop.example.com": {Concurrency: 1, Delay: 3 * time.Second},
+		},
+	}, options)
+}
+
+func Test_OptionsFromMap_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Data     map[string]any
+	}{
+		{"UnknownOption", map[string]any{"worker": 2}},
+		{"InvalidType", map[string]any{"workers": "2"}},
+		{"NoWorkers", map[string]any{"workers": 0}},
+		{"NegativeQueueSize", map[string]any{"queue_size": -1}},
+		{"InvalidFullQueue", map[string]any{"full_queue": "wait"}},
+		{"InvalidHostDelay", map[string]any{"host_delay": "1s"}},
+		{"NegativeHostConcurrency", map[string]any{"host_concurrency": -1}},
+		{"HostsNotAMap", map[string]any{"hosts": []any{"shop.example.com"}}},
+		{"HostLimitsNotAMap", map[string]any{"hosts": map[string]any{"shop.example.com": 1}}},
+		{"UnknownHostOption", map[string]any{"hosts": map[string]any{"shop.example.com": map[string]any{"delays": 1}}}},
+		{"NegativeHostDelay", map[string]any{"hosts": map[string]any{"shop.example.com": map[string]any{"delay": -1}}}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			_, err := OptionsFromMap(tv.Data)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hotalert/alert"
+	"hotalert/httpclient"
+	"hotalert/task"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// usagePollInterval is the interval at which the memory and cpu usage of the worker processes is checked.
+var usagePollInterval = 100 * time.Millisecond
+
+// The types of the messages sent by worker processes.
+const (
+	workerMessageAlert  = "alert"
+	workerMessageResult = "result"
+)
+
+// ProcessOptions are the options of the worker processes of a ProcessExecutor.
+type ProcessOptions struct {
+	// Command is the command which starts a worker process, a program which calls RunWorker.
+	Command []string
+	// MemoryLimit is the maximum resident memory of a worker process in bytes, zero means no limit.
+	MemoryLimit int64
+	// CpuLimit is the maximum cpu time of a worker process, zero means no limit.
+	CpuLimit time.Duration
+}
+
+// DefaultProcessOptions returns options which start the worker processes with the worker command of the running
+// executable, without limits.
+func DefaultProcessOptions() ProcessOptions {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	return ProcessOptions{Command: []string{executable, "worker"}}
+}
+
+// Validate returns an error if the options are not valid.
+func (o *ProcessOptions) Validate() error {
+	if len(o.Command) == 0 {
+		return errors.New("invalid worker command, cannot be empty")
+	}
+	if o.MemoryLimit < 0 {
+		return errors.New(fmt.Sprintf("invalid memory limit %d, cannot be negative", o.MemoryLimit))
+	}
+	if o.CpuLimit < 0 {
+		return errors.New(fmt.Sprintf("invalid cpu limit %s, cannot be negative", o.CpuLimit))
+	}
+	if (o.MemoryLimit > 0 || o.CpuLimit > 0) && !limitsSupported {
+		return errors.New(fmt.Sprintf("memory and cpu limits are not supported on %s", runtime.GOOS))
+	}
+	return nil
+}
+
+// ProcessExecutor is an Executor which runs each task in a separate worker process, so that a task function which
+// hangs, crashes or uses too much memory does not affect the executor.
+// The worker process is killed when the task times out, when the executor shuts down and when the process exceeds its
+// memory or cpu limit. The alerts of the task are forwarded to the executor, which posts them with the task alerter.
+// The task functions must be registered in the worker program as well. The host limits of the executor do not apply
+// to the requests of the worker processes.
+// The queue, the batches, the retries and the middleware work as with the DefaultExecutor.
+type ProcessExecutor struct {
+	*DefaultExecutor
+	processOptions ProcessOptions
+}
+
+// NewProcessExecutor returns a new instance of ProcessExecutor given its options.
+// It returns an error if the options are invalid.
+func NewProcessExecutor(options Options, processOptions ProcessOptions) (*ProcessExecutor, error) {
+	if err := processOptions.Validate(); err != nil {
+		return nil, err
+	}
+	defaultExecutor, err := NewDefaultExecutorWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	pe := &ProcessExecutor{DefaultExecutor: defaultExecutor, processOptions: processOptions}
+	defaultExecutor.functionHandler = func(function task.Function) Handler {
+		return pe.runWorker
+	}
+	return pe, nil
+}
+
+// workerRequest is the task sent to a worker process on its standard input.
+type workerRequest struct {
+	Function    string              `json:"function"`
+	Options     task.Options        `json:"options"`
+	Timeout     time.Duration       `json:"timeout"`
+	HttpOptions *httpclient.Options `json:"http_options,omitempty"`
+}
+
+// workerMessage is a message sent by a worker process on its standard output, an alert posted by the task or the
+// result of the task, which is the last message.
+type workerMessage struct {
+	Type     string   `json:"type"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Error is the error message of the task, or the value given to panic when Panic is set.
+	Error     string            `json:"error,omitempty"`
+	ErrorKind string            `json:"error_kind,omitempty"`
+	Panic     bool              `json:"panic,omitempty"`
+	Outputs   map[string]string `json:"outputs,omitempty"`
+}
+
+// err returns the error of the task given by a result message.
+func (m *workerMessage) err() error {
+	switch {
+	case m.Panic:
+		return &PanicError{Value: m.Error}
+	case m.Error != "":
+		return &task.KindError{Message: m.Error, Kind: m.ErrorKind}
+	}
+	return nil
+}
+
+// runWorker executes the task in a worker process.
+func (pe *ProcessExecutor) runWorker(ctx context.Context, currentTask *task.Task) error {
+	request, err := json.Marshal(workerRequest{
+		Function:    currentTask.ExecutionFuncName,
+		Options:     currentTask.Options,
+		Timeout:     currentTask.Timeout,
+		HttpOptions: currentTask.HttpOptions,
+	})
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to encode the task: %s", err))
+	}
+
+	command := exec.Command(pe.processOptions.Command[0], pe.processOptions.Command[1:]...)
+	command.Stdin = bytes.NewReader(request)
+	command.Stderr = os.Stderr
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	configureProcess(command)
+	if err := command.Start(); err != nil {
+		return errors.New(fmt.Sprintf("failed to start worker process: %s", err))
+	}
+
+	var exited = make(chan struct{})
+	var limitErr = make(chan error, 1)
+	go pe.watchWorker(ctx, command, exited, limitErr)
+
+	var result *workerMessage
+	decoder := json.NewDecoder(stdout)
+	for {
+		var message workerMessage
+		if err := decoder.Decode(&message); err != nil {
+			// Unread output would block the worker process.
+			_, _ = io.Copy(io.Discard, stdout)
+			break
+		}
+		switch message.Type {
+		case workerMessageAlert:
+			currentTask.Alerter.PostAlert(ctx, message.Keywords)
+		case workerMessageResult:
+			result = &message
+		}
+	}
+	waitErr := command.Wait()
+	close(exited)
+
+	select {
+	case err := <-limitErr:
+		return err
+	default:
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("worker process killed: %w", ctx.Err())
+	}
+	if result == nil {
+		if waitErr != nil {
+			return errors.New(fmt.Sprintf("worker process failed: %s", waitErr))
+		}
+		return errors.New("worker process exited without a result")
+	}
+	for name, value := range result.Outputs {
+		task.SetOutput(ctx, name, value)
+	}
+	return result.err()
+}
+
+// watchWorker kills the worker process when the context is done or when it exceeds its limits, until it exits.
+// The limit error is sent to limitErr before the process is killed.
+func (pe *ProcessExecutor) watchWorker(ctx context.Context, command *exec.Cmd, exited <-chan struct{}, limitErr chan<- error) {
+	var ticks <-chan time.Time
+	if pe.processOptions.MemoryLimit > 0 || pe.processOptions.CpuLimit > 0 {
+		ticker := time.NewTicker(usagePollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+			killProcess(command)
+			return
+		case <-ticks:
+			memory, cpu, err := processUsage(command.Process.Pid)
+			if err != nil {
+				// The process exited.
+				continue
+			}
+			switch {
+			case pe.processOptions.MemoryLimit > 0 && memory > pe.processOptions.MemoryLimit:
+				limitErr <- errors.New(fmt.Sprintf("worker process exceeded its memory limit of %d bytes", pe.processOptions.MemoryLimit))
+			case pe.processOptions.CpuLimit > 0 && cpu > pe.processOptions.CpuLimit:
+				limitErr <- errors.New(fmt.Sprintf("worker process exceeded its cpu limit of %s", pe.processOptions.CpuLimit))
+			default:
+				continue
+			}
+			killProcess(command)
+			return
+		}
+	}
+}
+
+// workerEncoder writes the messages of a worker process, the task function may post alerts concurrently.
+type workerEncoder struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func (e *workerEncoder) encode(message workerMessage) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.encoder.Encode(message)
+}
+
+// forwardingAlerter is the alerter of the tasks executed by a worker process, it forwards the alerts to the executor.
+type forwardingAlerter struct {
+	encoder *workerEncoder
+}
+
+func (a *forwardingAlerter) PostAlert(ctx context.Context, matchedKeywords []string) {
+	_ = a.encoder.encode(workerMessage{Type: workerMessageAlert, Keywords: matchedKeywords})
+}
+
+// RunWorker executes the task given by a ProcessExecutor on the input and writes its alerts and result to the output.
+// It is called by the worker program, which must register the same task functions as the executor program, and
+// which must not write anything else to the output.
+// It returns an error if the task cannot be read or built, the error of the task is part of the result.
+func RunWorker(input io.Reader, output io.Writer) error {
+	var request workerRequest
+	if err := json.NewDecoder(input).Decode(&request); err != nil {
+		return errors.New(fmt.Sprintf("failed to read the task: %s", err))
+	}
+	function, ok := functionMap[request.Function]
+	if !ok {
+		return errors.New(fmt.Sprintf("invalid task execution function name: '%s'", request.Function))
+	}
+
+	encoder := &workerEncoder{encoder: json.NewEncoder(output)}
+	currentTask, err := newWorkerTask(request, &forwardingAlerter{encoder: encoder})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := taskContext(context.Background(), currentTask)
+	defer cancel()
+	outputs := task.NewOutputs()
+	err = recoverPanic(function)(task.ContextWithOutputs(ctx, outputs), currentTask)
+	return encoder.encode(resultMessage(err, outputs.Values()))
+}
+
+// newWorkerTask returns the task given by a request, which posts its alerts with the alerter.
+func newWorkerTask(request workerRequest, alerter alert.Alerter) (*task.Task, error) {
+	currentTask := &task.Task{
+		ExecutionFuncName: request.Function,
+		Options:           request.Options,
+		Timeout:           request.Timeout,
+		Alerter:           alerter,
+		HttpOptions:       request.HttpOptions,
+	}
+	if request.HttpOptions != nil {
+		client, err := httpclient.New(*request.HttpOptions)
+		if err != nil {
+			return nil, err
+		}
+		currentTask.HttpClient = client
+	}
+	return currentTask, nil
+}
+
+// resultMessage returns the result message of a task given its error and outputs.
+func resultMessage(err error, outputs map[string]string) workerMessage {
+	var result = workerMessage{Type: workerMessageResult, Outputs: outputs}
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		result.Panic = true
+		result.Error = fmt.Sprint(panicErr.Value)
+	case err != nil:
+		result.Error = err.Error()
+		result.ErrorKind = task.ErrorKind(err)
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+//go:build linux
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// limitsSupported is true when the memory and cpu usage of the worker processes can be measured.
+const limitsSupported = true
+
+// clockTicks is the number of clock ticks per second in which /proc reports cpu times.
+const clockTicks = 100
+
+// configureProcess starts the worker process in its own process group, so that killProcess kills the processes it
+// started as well and so that the signals sent to the executor from the terminal do not reach it.
+func configureProcess(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcess kills the process group of the worker process.
+func killProcess(command *exec.Cmd) {
+	_ = syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+}
+
+// processUsage returns the resident memory in bytes and the cpu time of the process.
+func processUsage(pid int) (int64, time.Duration, error) {
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	statmFields := strings.Fields(string(statm))
+	if len(statmFields) < 2 {
+		return 0, 0, errors.New(fmt.Sprintf("invalid statm of process %d", pid))
+	}
+	residentPages, err := strconv.ParseInt(statmFields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The fields follow the command name, which is in parentheses and may contain spaces. The user and system cpu
+	// times are the 14th and 15th fields.
+	statFields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	if len(statFields) < 13 {
+		return 0, 0, errors.New(fmt.Sprintf("invalid stat of process %d", pid))
+	}
+	var ticks int64
+	for _, field := range statFields[11:13] {
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		ticks += value
+	}
+	return residentPages * int64(os.Getpagesize()), time.Duration(ticks) * time.Second / clockTicks, nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// limitsSupported is true when the memory and cpu usage of the worker processes can be measured.
+const limitsSupported = false
+
+// configureProcess does nothing, the worker processes are started in the process group of the executor.
+func configureProcess(command *exec.Cmd) {
+}
+
+// killProcess kills the worker process.
+func killProcess(command *exec.Cmd) {
+	_ = command.Process.Kill()
+}
+
+// processUsage is not supported.
+func processUsage(pid int) (int64, time.Duration, error) {
+	return 0, 0, errors.New("process usage is not supported")
+}
@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"hotalert/logging"
+	"hotalert/task"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// workerEnv makes the test binary run as a worker process, see TestMain.
+const workerEnv = "HOTALERT_TEST_WORKER"
+
+// The functions executed by the worker processes of the tests, they are registered in the test process and in the
+// worker processes.
+var workerTestFunctions = map[string]ExecutionFunc{
+	"worker_test_alert": func(ctx context.Context, currentTask *task.Task) error {
+		currentTask.Alerter.PostAlert(ctx, []string{currentTask.Options["keyword"].(string)})
+		task.SetOutput(ctx, "pid", "worker")
+		return nil
+	},
+	"worker_test_status": func(ctx context.Context, currentTask *task.Task) error {
+		return &task.StatusCodeError{Message: "Failed to query website", StatusCode: 503}
+	},
+	"worker_test_panic": func(ctx context.Context, currentTask *task.Task) error {
+		panic("test")
+	},
+	"worker_test_exit": func(ctx context.Context, currentTask *task.Task) error {
+		os.Exit(3)
+		return nil
+	},
+	"worker_test_hang": func(ctx context.Context, currentTask *task.Task) error {
+		time.Sleep(time.Minute)
+		return nil
+	},
+	"worker_test_memory": func(ctx context.Context, currentTask *task.Task) error {
+		var chunks [][]byte
+		for {
+			chunk := make([]byte, 1024*1024)
+			for i := range chunk {
+				chunk[i] = 1
+			}
+			chunks = append(chunks, chunk)
+			time.Sleep(time.Millisecond)
+		}
+	},
+	"worker_test_cpu": func(ctx context.Context, currentTask *task.Task) error {
+		for {
+		}
+	},
+}
+
+func TestMain(m *testing.M) {
+	for name, function := range workerTestFunctions {
+		_ = RegisterNewExecutionFunction(name, function)
+	}
+	if os.Getenv(workerEnv) != "" {
+		output := os.Stdout
+		os.Stdout = os.Stderr
+		logging.InitLoggingWithParams("info", "console")
+		if err := RunWorker(os.Stdin, output); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestProcessExecutor returns a started ProcessExecutor whose worker processes run the test binary.
+func newTestProcessExecutor(t *testing.T, processOptions ProcessOptions) *ProcessExecutor {
+	t.Setenv(workerEnv, "1")
+	processOptions.Command = []string{os.Args[0]}
+	processExecutor, err := NewProcessExecutor(DefaultOptions(), processOptions)
+	assert.NoError(t, err)
+	processExecutor.Start()
+	return processExecutor
+}
+
+// recordingAlerter records the keywords of the alerts.
+type recordingAlerter struct {
+	alerts chan []string
+}
+
+func (a *recordingAlerter) PostAlert(ctx context.Context, matchedKeywords []string) {
+	a.alerts <- matchedKeywords
+}
+
+func Test_ProcessExecutor(t *testing.T) {
+	processExecutor := newTestProcessExecutor(t, ProcessOptions{})
+	defer processExecutor.Shutdown()
+
+	alerter := &recordingAlerter{alerts: make(chan []string, 1)}
+	report := processExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: "worker_test_alert",
+		Options:           task.Options{"keyword": "In stock"},
+		Timeout:           10 * time.Second,
+		Alerter:           alerter,
+	}}).Wait()
+
+	assert.NoError(t, report.Results[0].Error())
+	assert.Equal(t, map[string]string{"pid": "worker"}, report.Results[0].Outputs())
+	assert.Equal(t, []string{"In stock"}, <-alerter.alerts)
+}
+
+func Test_ProcessExecutor_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName       string
+		FunctionName   string
+		ProcessOptions ProcessOptions
+		Timeout        time.Duration
+		Linux          bool
+		ExpectedError  string
+	}{
+		{"Panic", "worker_test_panic", ProcessOptions{}, 10 * time.Second, false, "panic: test"},
+		{"Crash", "worker_test_exit", ProcessOptions{}, 10 * time.Second, false, "worker process failed: exit status 3"},
+		{"Timeout", "worker_test_hang", ProcessOptions{}, 200 * time.Millisecond, false, "task timed out after 200ms: context deadline exceeded"},
+		{"MemoryLimit", "worker_test_memory", ProcessOptions{MemoryLimit: 64 * 1024 * 1024}, 10 * time.Second, true, "worker process exceeded its memory limit of 67108864 bytes"},
+		{"CpuLimit", "worker_test_cpu", ProcessOptions{CpuLimit: 300 * time.Millisecond}, 10 * time.Second, true, "worker process exceeded its cpu limit of 300ms"},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			if tv.Linux && runtime.GOOS != "linux" {
+				t.Skip("limits are only supported on linux")
+			}
+			processExecutor := newTestProcessExecutor(t, tv.ProcessOptions)
+			defer processExecutor.Shutdown()
+
+			start := time.Now()
+			report := processExecutor.SubmitBatch([]*task.Task{{
+				ExecutionFuncName: tv.FunctionName,
+				Timeout:           tv.Timeout,
+				Alerter:           alert.NewDummyAlerter(),
+			}}).Wait()
+			assert.EqualError(t, report.Results[0].Error(), tv.ExpectedError)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func Test_ProcessExecutor_Retry(t *testing.T) {
+	processExecutor := newTestProcessExecutor(t, ProcessOptions{})
+	defer processExecutor.Shutdown()
+
+	report := processExecutor.SubmitBatch([]*task.Task{{
+		ExecutionFuncName: "worker_test_status",
+		Timeout:           10 * time.Second,
+		Retry:             task.RetryPolicy{Retries: 1, RetryOn: []string{task.RetryOn5xx}},
+		Alerter:           alert.NewDummyAlerter(),
+	}}).Wait()
+
+	// The kind of the error is kept, so the task is retried.
+	assert.EqualError(t, report.Results[0].Error(), "Failed to query website, status code 503")
+	assert.Equal(t, 2, report.Results[0].Attempts())
+}
+
+func Test_ProcessOptions_Validate(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Options       ProcessOptions
+		ExpectedError bool
+	}{
+		{"Default", DefaultProcessOptions(), false},
+		{"EmptyCommand", ProcessOptions{}, true},
+		{"NegativeMemoryLimit", ProcessOptions{Command: []string{"hotalert"}, MemoryLimit: -1}, true},
+		{"NegativeCpuLimit", ProcessOptions{Command: []string{"hotalert"}, CpuLimit: -time.Second}, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			err := tv.Options.Validate()
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_RunWorker_InvalidRequest(t *testing.T) {
+	err := RunWorker(errorReader{}, os.Stderr)
+	assert.Error(t, err)
+}
+
+// errorReader is a reader which always fails.
+type errorReader struct{}
+
+func (errorReader) Read(p []byte) (int, error) {
+	return 0, errors.New("test")
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// taskQueue is the bounded task queue of the DefaultExecutor.
+// Tasks with a higher priority are taken first. Tasks of the same priority are taken in turns from each workload, in
+// the order in which they were added within a workload, so that a large workload does not starve small ones.
+type taskQueue struct {
+	// room has a value for each task which can be added without waiting. Like a channel, the queue holds size tasks
+	// plus one for each worker waiting in pop, so a queue of size zero hands tasks over to idle workers.
+	room chan struct{}
+	// available has a value for each task which can be taken, it is closed when the queue is closed.
+	available chan struct{}
+	// mutex guards levels.
+	mutex sync.Mutex
+	// levels holds the queued tasks by priority, sorted by decreasing priority.
+	levels []*priorityLevel
+	// stopping is closed when no more tasks may be pushed, it wakes up the pushers waiting for room.
+	stopping chan struct{}
+	// stopOnce closes stopping once.
+	stopOnce sync.Once
+}
+
+// priorityLevel holds the queued tasks of a priority by workload.
+type priorityLevel struct {
+	priority int
+	// workloads holds the names of the workloads which have queued tasks, the next task is taken from the first one.
+	workloads []string
+	tasks     map[string][]queuedTask
+}
+
+// newTaskQueue returns a new empty taskQueue which holds up to size tasks and is read by up to workers goroutines.
+func newTaskQueue(size int, workers int) *taskQueue {
+	// One more reader takes the remaining tasks on shutdown.
+	capacity := size + workers + 1
+	q := &taskQueue{
+		room:      make(chan struct{}, capacity),
+		available: make(chan struct{}, capacity),
+		stopping:  make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		q.room <- struct{}{}
+	}
+	return q
+}
+
+// push adds the task to the queue, waiting for room until the context is done or stopPushing is called.
+// It returns false if the context is done or pushing is stopped first.
+func (q *taskQueue) push(ctx context.Context, queued queuedTask) bool {
+	select {
+	case <-q.room:
+	case <-ctx.Done():
+		return false
+	case <-q.stopping:
+		return false
+	}
+	q.add(queued)
+	return true
+}
+
+// tryPush adds the task to the queue if it has room. It returns false if the queue is full.
+func (q *taskQueue) tryPush(queued queuedTask) bool {
+	select {
+	case <-q.room:
+	default:
+		return false
+	}
+	q.add(queued)
+	return true
+}
+
+// add adds the task to its priority level and workload once it has room.
+func (q *taskQueue) add(queued queuedTask) {
+	q.mutex.Lock()
+	level := q.level(queued.task.Priority)
+	workload := queued.task.Workload
+	if len(level.tasks[workload]) == 0 {
+		level.workloads = append(level.workloads, workload)
+	}
+	level.tasks[workload] = append(level.tasks[workload], queued)
+	q.mutex.Unlock()
+	q.available <- struct{}{}
+}
+
+// level returns the level of the priority, adding it if needed. The mutex must be held.
+func (q *taskQueue) level(priority int) *priorityLevel {
+	i := sort.Search(len(q.levels), func(i int) bool {
+		return q.levels[i].priority <= priority
+	})
+	if i < len(q.levels) && q.levels[i].priority == priority {
+		return q.levels[i]
+	}
+	level := &priorityLevel{priority: priority, tasks: make(map[string][]queuedTask)}
+	q.levels = append(q.levels, nil)
+	copy(q.levels[i+1:], q.levels[i:])
+	q.levels[i] = level
+	return level
+}
+
+// pop waits for a task and removes it from the queue. It returns false once the queue is closed and empty.
+// The room of the task is given back when pop is called, not when it returns, which lets a task be added while a
+// worker waits.
+func (q *taskQueue) pop() (queuedTask, bool) {
+	q.room <- struct{}{}
+	if _, ok := <-q.available; !ok {
+		return queuedTask{}, false
+	}
+	q.mutex.Lock()
+	level := q.levels[0]
+	workload := level.workloads[0]
+	queued := level.tasks[workload][0]
+	level.tasks[workload] = level.tasks[workload][1:]
+	// The workload goes to the back of the line if it has more tasks.
+	level.workloads = level.workloads[1:]
+	if len(level.tasks[workload]) > 0 {
+		level.workloads = append(level.workloads, workload)
+	} else {
+		delete(level.tasks, workload)
+	}
+	if len(level.workloads) == 0 {
+		q.levels = q.levels[1:]
+	}
+	q.mutex.Unlock()
+	return queued, true
+}
+
+// stopPushing makes the pushers which wait for room, now or later, give up.
+// It may be called more than once.
+func (q *taskQueue) stopPushing() {
+	q.stopOnce.Do(func() {
+		close(q.stopping)
+	})
+}
+
+// close closes the queue, pop returns the remaining tasks and then false. No task may be pushed after close.
+func (q *taskQueue) close() {
+	close(q.available)
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"testing"
+	"time"
+)
+
+func Test_taskQueue_Order(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		Tasks         []*task.Task
+		ExpectedOrder []string
+	}{
+		{
+			"Fifo",
+			[]*task.Task{{Id: "a"}, {Id: "b"}, {Id: "c"}},
+			[]string{"a", "b", "c"},
+		},
+		{
+			"Priority",
+			[]*task.Task{{Id: "a"}, {Id: "b", Priority: 10}, {Id: "c", Priority: -1}, {Id: "d", Priority: 10}},
+			[]string{"b", "d", "a", "c"},
+		},
+		{
+			"FairWorkloads",
+			[]*task.Task{
+				{Id: "big1", Workload: "big.yaml"},
+				{Id: "big2", Workload: "big.yaml"},
+				{Id: "big3", Workload: "big.yaml"},
+				{Id: "small1", Workload: "small.yaml"},
+				{Id: "small2", Workload: "small.yaml"},
+				{Id: "other1", Workload: "other.yaml"},
+			},
+			[]string{"big1", "small1", "other1", "big2", "small2", "big3"},
+		},
+		{
+			"PriorityBeforeWorkloads",
+			[]*task.Task{
+				{Id: "big1", Workload: "big.yaml"},
+				{Id: "big2", Workload: "big.yaml"},
+				{Id: "critical", Workload: "small.yaml", Priority: 1},
+				{Id: "small1", Workload: "small.yaml"},
+			},
+			[]string{"critical", "big1", "small1", "big2"},
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			queue := newTaskQueue(len(tv.Tasks), 0)
+			for _, currentTask := range tv.Tasks {
+				assert.True(t, queue.tryPush(queuedTask{task: currentTask}))
+			}
+			queue.close()
+
+			var order []string
+			for {
+				queued, ok := queue.pop()
+				if !ok {
+					break
+				}
+				order = append(order, queued.task.Id)
+			}
+			assert.Equal(t, tv.ExpectedOrder, order)
+		})
+	}
+}
+
+func Test_taskQueue_Full(t *testing.T) {
+	queue := newTaskQueue(1, 1)
+	assert.True(t, queue.tryPush(queuedTask{task: &task.Task{Id: "a"}}))
+	assert.False(t, queue.tryPush(queuedTask{task: &task.Task{Id: "b"}}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.False(t, queue.push(ctx, queuedTask{task: &task.Task{Id: "b"}}))
+
+	// Taking a task makes room for a waiting one.
+	var pushed = make(chan bool)
+	go func() {
+		pushed <- queue.push(context.Background(), queuedTask{task: &task.Task{Id: "b"}})
+	}()
+	queued, ok := queue.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "a", queued.task.Id)
+	assert.True(t, <-pushed)
+	queued, ok = queue.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "b", queued.task.Id)
+}
@@ -0,0 +1,161 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"hotalert/logging"
+	"reflect"
+	"strings"
+)
+
+// OptionsValidator is implemented by the options struct of a task function.
+type OptionsValidator interface {
+	// Validate returns an error if the decoded options are not valid.
+	Validate() error
+}
+
+// Function is the interface implemented by task functions.
+type Function interface {
+	// Description returns a short description of the function, which is shown in the help output.
+	Description() string
+	// OptionNames returns the names of the options accepted by the function.
+	OptionNames() []string
+	// ValidateOptions decodes and validates the task options without executing the function.
+	ValidateOptions(options Options) error
+	// Execute executes the task and returns an error if the execution failed.
+	// The context is cancelled when the task times out or the executor shuts down.
+	Execute(ctx context.Context, task *Task) error
+}
+
+// OutputEscaper is implemented by the functions which expand ${...} placeholders of their own in the options.
+// The outputs of other tasks are escaped before they are inserted in the options, so that the placeholders they
+// contain are kept as they are.
+type OutputEscaper interface {
+	// EscapeOutput returns the output value with its placeholders escaped.
+	EscapeOutput(value string) string
+}
+
+// typedFunction is a Function whose options are decoded into an options struct of type O.
+type typedFunction[O OptionsValidator] struct {
+	description string
+	newOptions  func() O
+	execute     func(ctx context.Context, task *Task, options O) error
+}
+
+// NewFunction returns a new Function given its description, a function which returns a pointer to the options
+// struct filled with the default values, and the function which executes the task given its decoded options.
+// The options struct uses mapstructure tags, unknown options are rejected.
+func NewFunction[O OptionsValidator](description string, newOptions func() O, execute func(ctx context.Context, task *Task, options O) error) Function {
+	return &typedFunction[O]{
+		description: description,
+		newOptions:  newOptions,
+		execute:     execute,
+	}
+}
+
+// Description returns the description of the function.
+func (f *typedFunction[O]) Description() string {
+	return f.description
+}
+
+// OptionNames returns the names of the options in the options struct.
+func (f *typedFunction[O]) OptionNames() []string {
+	return optionNames(reflect.TypeOf(f.newOptions()))
+}
+
+// ValidateOptions decodes and validates the options.
+func (f *typedFunction[O]) ValidateOptions(options Options) error {
+	_, err := f.decode(options)
+	return err
+}
+
+// Execute decodes the task options and executes the task.
+func (f *typedFunction[O]) Execute(ctx context.Context, task *Task) error {
+	options, err := f.decode(task.Options)
+	if err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	return f.execute(ctx, task, options)
+}
+
+// decode returns the decoded and validated options.
+func (f *typedFunction[O]) decode(options Options) (O, error) {
+	decodedOptions := f.newOptions()
+	if err := DecodeOptions(options, decodedOptions); err != nil {
+		return decodedOptions, err
+	}
+	if err := decodedOptions.Validate(); err != nil {
+		return decodedOptions, err
+	}
+	return decodedOptions, nil
+}
+
+// DecodeOptions decodes the options into the target, a pointer to a struct with mapstructure tags.
+// Fields of the target which are missing from options keep their values, so the target holds the defaults.
+// Unknown options are an error, scalars in string maps are converted to strings and integers must be whole numbers.
+func DecodeOptions(options Options, target any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.ComposeDecodeHookFunc(stringMapHook, wholeNumberHook),
+		ErrorUnused: true,
+		ZeroFields:  true,
+		Result:      target,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(map[string]any(options)); err != nil {
+		return errors.New(fmt.Sprintf("invalid task options: %s", strings.Replace(err.Error(), "\n", " ", -1)))
+	}
+	return nil
+}
+
+// stringMapHook converts the scalar values of maps decoded into a map[string]string, such as headers or query
+// parameters, to strings.
+func stringMapHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	rawMap, ok := data.(map[string]any)
+	if !ok || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+	values := make(map[string]string, len(rawMap))
+	for key, value := range rawMap {
+		switch value.(type) {
+		case string, int, int64, float64, bool:
+			values[key] = fmt.Sprint(value)
+		default:
+			return nil, errors.New(fmt.Sprintf("%s is not a scalar %v", key, value))
+		}
+	}
+	return values, nil
+}
+
+// wholeNumberHook rejects numbers with a fraction decoded into integers.
+func wholeNumberHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	value, ok := data.(float64)
+	if ok && to.Kind() == reflect.Int && value != float64(int(value)) {
+		return nil, errors.New(fmt.Sprintf("%v is not an integer", value))
+	}
+	return data, nil
+}
+
+// optionNames returns the mapstructure names of the fields of the struct type, or of the struct the type points to.
+// The fields of squashed embedded structs are included.
+func optionNames(structType reflect.Type) []string {
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	var names = make([]string, 0, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, flags, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		switch {
+		case flags == "squash":
+			names = append(names, optionNames(field.Type)...)
+		case name != "" && name != "-":
+			names = append(names, name)
+		}
+	}
+	return names
+}
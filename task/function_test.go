@@ -0,0 +1,96 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"testing"
+)
+
+type testRequestOptions struct {
+	Method  string            `mapstructure:"method"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+type testFunctionOptions struct {
+	Url                string   `mapstructure:"url"`
+	Keywords           []string `mapstructure:"keywords"`
+	Retries            int      `mapstructure:"retries"`
+	Threshold          *float64 `mapstructure:"threshold"`
+	testRequestOptions `mapstructure:",squash"`
+	ignored            string
+}
+
+func (o *testFunctionOptions) Validate() error {
+	if o.Url == "" {
+		return errors.New("url is required")
+	}
+	return nil
+}
+
+func newTestFunction(executed *testFunctionOptions) Function {
+	return NewFunction(
+		"Test function.",
+		func() *testFunctionOptions {
+			return &testFunctionOptions{Retries: 3, testRequestOptions: testRequestOptions{Method: "GET"}}
+		},
+		func(ctx context.Context, task *Task, options *testFunctionOptions) error {
+			*executed = *options
+			return nil
+		},
+	)
+}
+
+func Test_NewFunction(t *testing.T) {
+	var executed testFunctionOptions
+	function := newTestFunction(&executed)
+	assert.Equal(t, "Test function.", function.Description())
+	assert.Equal(t, []string{"url", "keywords", "retries", "threshold", "method", "headers"}, function.OptionNames())
+
+	err := function.Execute(context.Background(), NewTask("test", Options{
+		"url":       "https://example.com",
+		"keywords":  []any{"a", "b"},
+		"threshold": 10,
+		"headers":   map[string]any{"X-Page": 2, "X-Debug": true},
+	}, alert.NewDummyAlerter()))
+	assert.NoError(t, err)
+	threshold := 10.0
+	assert.Equal(t, testFunctionOptions{
+		Url:                "https://example.com",
+		Keywords:           []string{"a", "b"},
+		Retries:            3,
+		Threshold:          &threshold,
+		testRequestOptions: testRequestOptions{Method: "GET", Headers: map[string]string{"X-Page": "2", "X-Debug": "true"}},
+	}, executed)
+}
+
+func Test_NewFunction_InvalidOptions(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Options  Options
+	}{
+		{"UnknownOption", Options{"url": "https://example.com", "keywrods": []any{"a"}}},
+		{"InvalidType", Options{"url": "https://example.com", "keywords": "a"}},
+		{"FractionalInteger", Options{"url": "https://example.com", "retries": 1.5}},
+		{"NonScalarMapValue", Options{"url": "https://example.com", "headers": map[string]any{"X-Page": []any{1}}}},
+		{"ValidationFailed", Options{"keywords": []any{"a"}}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			var executed testFunctionOptions
+			function := newTestFunction(&executed)
+			assert.Error(t, function.ValidateOptions(tv.Options))
+			assert.Error(t, function.Execute(context.Background(), NewTask("test", tv.Options, alert.NewDummyAlerter())))
+			assert.Empty(t, executed.Url)
+		})
+	}
+}
+
+func Test_DecodeOptions_ZeroFields(t *testing.T) {
+	options := testFunctionOptions{Keywords: []string{"a", "b", "c"}}
+	assert.NoError(t, DecodeOptions(Options{"keywords": []any{"d"}, "retries": 2.0}, &options))
+	assert.Equal(t, []string{"d"}, options.Keywords)
+	assert.Equal(t, 2, options.Retries)
+}
@@ -0,0 +1,25 @@
+package functions
+
+import (
+	"context"
+	"sync"
+)
+
+// recordingAlerter is an alert.Alerter that records the posted alerts.
+type recordingAlerter struct {
+	mutex  sync.Mutex
+	alerts [][]string
+}
+
+func (r *recordingAlerter) PostAlert(ctx context.Context, matchedKeywords []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.alerts = append(r.alerts, matchedKeywords)
+}
+
+// Alerts returns the alerts posted so far.
+func (r *recordingAlerter) Alerts() [][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.alerts
+}
@@ -0,0 +1,340 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Match modes supported by the match option.
+const (
+	matchAny  = "any"
+	matchAll  = "all"
+	matchNone = "none"
+)
+
+// keywordCondition decides whether an alert is posted given the keywords found in a page.
+type keywordCondition interface {
+	// Evaluate returns true if the condition holds for the found keywords.
+	Evaluate(found map[string]bool) bool
+	// String returns the condition as an expression.
+	String() string
+}
+
+// keywordNode is a condition which holds when the keyword was found.
+type keywordNode struct {
+	keyword string
+}
+
+func (n *keywordNode) Evaluate(found map[string]bool) bool {
+	return found[n.keyword]
+}
+
+func (n *keywordNode) String() string {
+	return fmt.Sprintf("%q", n.keyword)
+}
+
+// notNode is a condition which holds when its operand does not hold.
+type notNode struct {
+	operand keywordCondition
+}
+
+func (n *notNode) Evaluate(found map[string]bool) bool {
+	return !n.operand.Evaluate(found)
+}
+
+func (n *notNode) String() string {
+	return "not " + n.operand.String()
+}
+
+// binaryNode is a condition which combines two conditions with and / or.
+type binaryNode struct {
+	operator string
+	left     keywordCondition
+	right    keywordCondition
+}
+
+func (n *binaryNode) Evaluate(found map[string]bool) bool {
+	if n.operator == "and" {
+		return n.left.Evaluate(found) && n.right.Evaluate(found)
+	}
+	return n.left.Evaluate(found) || n.right.Evaluate(found)
+}
+
+func (n *binaryNode) String() string {
+	return fmt.Sprintf("(%s %s %s)", n.left, n.operator, n.right)
+}
+
+// conditionParser is a recursive descent parser for keyword conditions. The grammar is:
+//
+//	expression = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expression ")" | quoted keyword
+type conditionParser struct {
+	tokens   []string
+	position int
+	keywords []string
+}
+
+// parseCondition parses a keyword condition such as `"In stock" and not "Pre-order"`.
+// It returns the condition and the keywords used in it.
+func parseCondition(expression string) (keywordCondition, []string, error) {
+	tokens, err := tokenizeCondition(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	parser := &conditionParser{tokens: tokens}
+	condition, err := parser.parseExpression()
+	if err != nil {
+		return nil, nil, err
+	}
+	if parser.position < len(parser.tokens) {
+		return nil, nil, errors.New(fmt.Sprintf("unexpected %s in condition", parser.tokens[parser.position]))
+	}
+	return condition, parser.keywords, nil
+}
+
+// tokenizeCondition splits the expression into quoted keywords, operators and parentheses.
+// Quoted keywords keep their double quotes, a backslash escapes the next character inside quotes.
+func tokenizeCondition(expression string) ([]string, error) {
+	var tokens = make([]string, 0, 8)
+	for i := 0; i < len(expression); {
+		switch character := expression[i]; {
+		case character == ' ' || character == '\t' || character == '\n':
+			i += 1
+		case character == '(' || character == ')':
+			tokens = append(tokens, string(character))
+			i += 1
+		case character == '"':
+			var keyword strings.Builder
+			keyword.WriteByte('"')
+			i += 1
+			for ; i < len(expression) && expression[i] != '"'; i++ {
+				if expression[i] == '\\' && i+1 < len(expression) {
+					i += 1
+				}
+				keyword.WriteByte(expression[i])
+			}
+			if i >= len(expression) {
+				return nil, errors.New("unterminated keyword in condition")
+			}
+			tokens = append(tokens, keyword.String())
+			i += 1
+		default:
+			start := i
+			for i < len(expression) && strings.IndexByte(" \t\n()\"", expression[i]) < 0 {
+				i += 1
+			}
+			word := strings.ToLower(expression[start:i])
+			if word != "and" && word != "or" && word != "not" {
+				return nil, errors.New(fmt.Sprintf("unexpected %s in condition, keywords must be quoted", expression[start:i]))
+			}
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens, nil
+}
+
+// peek returns the current token or an empty string at the end of the expression.
+func (p *conditionParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *conditionParser) parseExpression() (keywordCondition, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.position += 1
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseTerm() (keywordCondition, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.position += 1
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseFactor() (keywordCondition, error) {
+	token := p.peek()
+	p.position += 1
+	switch {
+	case token == "not":
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	case token == "(":
+		condition, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing ) in condition")
+		}
+		p.position += 1
+		return condition, nil
+	case strings.HasPrefix(token, "\""):
+		keyword := token[1:]
+		p.keywords = append(p.keywords, keyword)
+		return &keywordNode{keyword: keyword}, nil
+	case token == "":
+		return nil, errors.New("unexpected end of condition")
+	}
+	return nil, errors.New(fmt.Sprintf("unexpected %s in condition", token))
+}
+
+// keywordMatch holds the keyword options of a task and decides whether an alert is posted.
+type keywordMatch struct {
+	// Mode is one of matchAny, matchAll or matchNone. It is ignored when Condition is set.
+	Mode string
+	// Keywords are the keywords given in the keywords option.
+	Keywords []string
+	// Condition is the optional boolean expression over keywords.
+	Condition keywordCondition
+	// Expression is the condition as given in the condition option.
+	Expression string
+	// ConditionKeywords are the keywords used in Condition.
+	ConditionKeywords []string
+}
+
+// newKeywordMatch builds a keywordMatch given the match mode, the keywords and the optional condition expression.
+func newKeywordMatch(mode string, keywords []string, expression string) (*keywordMatch, error) {
+	match := &keywordMatch{Mode: strings.ToLower(mode), Keywords: uniqueStrings(keywords)}
+	if match.Mode != matchAny && match.Mode != matchAll && match.Mode != matchNone {
+		return nil, errors.New(fmt.Sprintf("invalid task parameter match %s", mode))
+	}
+	if expression != "" {
+		condition, conditionKeywords, err := parseCondition(expression)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid task parameter condition: %s", err))
+		}
+		match.Condition = condition
+		match.Expression = expression
+		match.ConditionKeywords = uniqueStrings(conditionKeywords)
+	} else if len(keywords) == 0 {
+		return nil, errors.New("invalid task parameter keywords, keywords or condition are required")
+	}
+	return match, nil
+}
+
+// SearchedKeywords returns all the keywords which have to be searched.
+func (m *keywordMatch) SearchedKeywords() []string {
+	if m.Condition != nil {
+		return m.ConditionKeywords
+	}
+	return m.Keywords
+}
+
+// Evaluate decides whether an alert is posted given the matched keywords.
+// It returns the alert context, which explains the condition that fired, or nil if no alert is posted.
+// In the default any mode the alert context is the list of matched keywords.
+// When truncated is set only a part of the page was searched, so the keywords which were not found may be in the rest
+// of the page. The alert is then only posted if the condition holds whether these keywords are present or not, so
+// the none mode and conditions which hold because a keyword was not found do not post alerts.
+func (m *keywordMatch) Evaluate(matchedKeywords []string, truncated bool) []string {
+	var found = make(map[string]bool, len(matchedKeywords))
+	for _, keyword := range matchedKeywords {
+		found[keyword] = true
+	}
+
+	if m.Condition != nil {
+		if truncated {
+			if holds, known := evaluatePartial(m.Condition, found); !holds || !known {
+				return nil
+			}
+		} else if !m.Condition.Evaluate(found) {
+			return nil
+		}
+		alertContext := []string{fmt.Sprintf("condition matched: %s", m.Expression)}
+		var notFound = make([]string, 0, len(m.ConditionKeywords))
+		for _, keyword := range m.ConditionKeywords {
+			if !found[keyword] {
+				notFound = append(notFound, keyword)
+			}
+		}
+		if len(matchedKeywords) > 0 {
+			alertContext = append(alertContext, fmt.Sprintf("found: %s", strings.Join(matchedKeywords, ", ")))
+		}
+		if len(notFound) > 0 {
+			alertContext = append(alertContext, fmt.Sprintf("not found: %s", strings.Join(notFound, ", ")))
+		}
+		return alertContext
+	}
+
+	switch m.Mode {
+	case matchAll:
+		if len(matchedKeywords) == len(m.Keywords) {
+			return []string{fmt.Sprintf("all keywords found: %s", strings.Join(matchedKeywords, ", "))}
+		}
+	case matchNone:
+		if len(matchedKeywords) == 0 && !truncated {
+			return []string{fmt.Sprintf("no keywords found: %s", strings.Join(m.Keywords, ", "))}
+		}
+	default:
+		if len(matchedKeywords) > 0 {
+			return matchedKeywords
+		}
+	}
+	return nil
+}
+
+// evaluatePartial evaluates the condition when the keywords which were not found may be present. It returns the value
+// of the condition and whether it is known, which is the case when it does not depend on these keywords.
+func evaluatePartial(condition keywordCondition, found map[string]bool) (holds bool, known bool) {
+	switch node := condition.(type) {
+	case *keywordNode:
+		return found[node.keyword], found[node.keyword]
+	case *notNode:
+		holds, known = evaluatePartial(node.operand, found)
+		return !holds, known
+	case *binaryNode:
+		leftHolds, leftKnown := evaluatePartial(node.left, found)
+		rightHolds, rightKnown := evaluatePartial(node.right, found)
+		if node.operator == "and" {
+			if (leftKnown && !leftHolds) || (rightKnown && !rightHolds) {
+				return false, true
+			}
+			return leftHolds && rightHolds, leftKnown && rightKnown
+		}
+		if (leftKnown && leftHolds) || (rightKnown && rightHolds) {
+			return true, true
+		}
+		return leftHolds || rightHolds, leftKnown && rightKnown
+	}
+	return false, false
+}
+
+// uniqueStrings returns the values without duplicates, keeping their order.
+func uniqueStrings(values []string) []string {
+	var seen = make(map[string]bool, len(values))
+	var unique = make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,143 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ParseCondition(t *testing.T) {
+	var tests = []struct {
+		Expression       string
+		ExpectedString   string
+		ExpectedKeywords []string
+	}{
+		{`"In stock"`, `"In stock"`, []string{"In stock"}},
+		{`"In stock" and not "Pre-order"`, `("In stock" and not "Pre-order")`, []string{"In stock", "Pre-order"}},
+		{`"a" or "b" and "c"`, `("a" or ("b" and "c"))`, []string{"a", "b", "c"}},
+		{`("a" OR "b") AND NOT ("c")`, `(("a" or "b") and not "c")`, []string{"a", "b", "c"}},
+		{`"say \"hi\""`, `"say \"hi\""`, []string{`say "hi"`}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.Expression, func(t *testing.T) {
+			condition, keywords, err := parseCondition(tv.Expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tv.ExpectedString, condition.String())
+			assert.Equal(t, tv.ExpectedKeywords, keywords)
+		})
+	}
+}
+
+func Test_ParseCondition_Errors(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`In stock`,
+		`"In stock" and`,
+		`"In stock" "Pre-order"`,
+		`("In stock"`,
+		`"In stock`,
+		`not`,
+		`)`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, _, err := parseCondition(expression)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_KeywordMatch_Evaluate(t *testing.T) {
+	var tests = []struct {
+		TestName             string
+		Mode                 string
+		Keywords             []string
+		Condition            string
+		MatchedKeywords      []string
+		ExpectedAlertContext []string
+	}{
+		{"AnyMatched", "any", []string{"a", "b"}, "", []string{"b"}, []string{"b"}},
+		{"AnyNotMatched", "any", []string{"a", "b"}, "", []string{}, nil},
+		{"AllMatched", "all", []string{"a", "b", "a"}, "", []string{"a", "b"}, []string{"all keywords found: a, b"}},
+		{"AllNotMatched", "ALL", []string{"a", "b"}, "", []string{"a"}, nil},
+		{"NoneMatched", "none", []string{"Out of stock"}, "", []string{}, []string{"no keywords found: Out of stock"}},
+		{"NoneNotMatched", "none", []string{"Out of stock"}, "", []string{"Out of stock"}, nil},
+		{
+			"ConditionMatched",
+			"any",
+			nil,
+			`"In stock" and not "Pre-order"`,
+			[]string{"In stock"},
+			[]string{`condition matched: "In stock" and not "Pre-order"`, "found: In stock", "not found: Pre-order"},
+		},
+		{
+			"ConditionNotMatched",
+			"any",
+			nil,
+			`"In stock" and not "Pre-order"`,
+			[]string{"In stock", "Pre-order"},
+			nil,
+		},
+		{
+			"ConditionOverridesMode",
+			"all",
+			[]string{"ignored"},
+			`not "Out of stock"`,
+			[]string{},
+			[]string{`condition matched: not "Out of stock"`, "not found: Out of stock"},
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			match, err := newKeywordMatch(tv.Mode, tv.Keywords, tv.Condition)
+			assert.NoError(t, err)
+			assert.Equal(t, tv.ExpectedAlertContext, match.Evaluate(tv.MatchedKeywords, false))
+		})
+	}
+}
+
+func Test_KeywordMatch_Evaluate_Truncated(t *testing.T) {
+	var tests = []struct {
+		TestName        string
+		Mode            string
+		Keywords        []string
+		Condition       string
+		MatchedKeywords []string
+		ExpectAlert     bool
+	}{
+		{"Any", "any", []string{"a", "b"}, "", []string{"a"}, true},
+		{"All", "all", []string{"a", "b"}, "", []string{"a", "b"}, true},
+		{"None", "none", []string{"Out of stock"}, "", []string{}, false},
+		{"ConditionFound", "any", nil, `"a" or not "b"`, []string{"a"}, true},
+		{"ConditionNotFound", "any", nil, `"a" and not "b"`, []string{"a"}, false},
+		{"ConditionFoundAndNot", "any", nil, `not ("a" and "b")`, []string{"a"}, false},
+		{"ConditionKnownFalse", "any", nil, `not "a" or ("b" and "c")`, []string{"a"}, false},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			match, err := newKeywordMatch(tv.Mode, tv.Keywords, tv.Condition)
+			assert.NoError(t, err)
+			assert.Equal(t, tv.ExpectAlert, match.Evaluate(tv.MatchedKeywords, true) != nil)
+		})
+	}
+}
+
+func Test_NewKeywordMatch_Errors(t *testing.T) {
+	_, err := newKeywordMatch("some", []string{"a"}, "")
+	assert.Error(t, err)
+	_, err = newKeywordMatch("any", nil, "")
+	assert.Error(t, err)
+	_, err = newKeywordMatch("any", nil, `"a" and`)
+	assert.Error(t, err)
+}
+
+func Test_KeywordMatch_SearchedKeywords(t *testing.T) {
+	match, err := newKeywordMatch("any", []string{"a"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, match.SearchedKeywords())
+
+	match, err = newKeywordMatch("any", []string{"a"}, `"b" or "c" or "b"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, match.SearchedKeywords())
+}
@@ -0,0 +1,82 @@
+package functions
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding header sent by task functions which decode the response body.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodeResponseBody returns a reader with the UTF-8 text of the response body.
+// The body is decompressed according to its Content-Encoding, PDF documents are converted to text and
+// other documents are transcoded from the charset declared in the Content-Type or in the document itself.
+// At most maxBodySize bytes are read for documents which have to be read in full, such as PDFs.
+func decodeResponseBody(resp *http.Response, maxBodySize int64) (io.Reader, error) {
+	body, err := decompressBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/pdf" {
+		return pdfText(body, maxBodySize)
+	}
+	reader, err := charset.NewReader(body, contentType)
+	if err == io.EOF {
+		// The body is empty.
+		return bytes.NewReader(nil), nil
+	}
+	return reader, err
+}
+
+// decompressBody returns a reader which decompresses the body according to the given Content-Encoding.
+func decompressBody(body io.Reader, contentEncoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// Deflate is supposed to be zlib wrapped but some servers send raw deflate data.
+		bufferedBody := bufio.NewReader(body)
+		header, err := bufferedBody.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(bufferedBody)
+		}
+		return flate.NewReader(bufferedBody), nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported content encoding %s", contentEncoding))
+}
+
+// pdfText reads the PDF document, up to maxBodySize bytes, and returns a reader with its text.
+func pdfText(body io.Reader, maxBodySize int64) (io.Reader, error) {
+	document, err := io.ReadAll(io.LimitReader(body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	pdfReader, err := pdf.NewReader(bytes.NewReader(document), int64(len(document)))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse pdf document: %s", err))
+	}
+	text, err := pdfReader.GetPlainText()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to extract text from pdf document: %s", err))
+	}
+	return text, nil
+}
@@ -0,0 +1,185 @@
+package functions
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// testPdfDocument builds a single page PDF document showing the given text.
+func testPdfDocument(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	var document bytes.Buffer
+	document.WriteString("%PDF-1.4\n")
+	var offsets = make([]int, 0, len(objects))
+	for i, object := range objects {
+		offsets = append(offsets, document.Len())
+		document.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, object))
+	}
+	xrefOffset := document.Len()
+	document.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, offset := range offsets {
+		document.WriteString(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	document.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset))
+	return document.Bytes()
+}
+
+func compress(t *testing.T, data []byte, newWriter func(io.Writer) io.WriteCloser) []byte {
+	var buffer bytes.Buffer
+	writer := newWriter(&buffer)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func Test_DecodeResponseBody(t *testing.T) {
+	iso88592, err := charmap.ISO8859_2.NewEncoder().Bytes([]byte("Žluťoučký kůň"))
+	assert.NoError(t, err)
+	windows1250, err := charmap.Windows1250.NewEncoder().Bytes([]byte(`<html><head><meta charset="windows-1250"></head><body>Ştefan cel Mare</body></html>`))
+	assert.NoError(t, err)
+	text := []byte("Episode 10 is out")
+
+	var tests = []struct {
+		TestName        string
+		Body            []byte
+		ContentType     string
+		ContentEncoding string
+		ExpectedText    string
+	}{
+		{
+			"Utf8",
+			text,
+			"text/html; charset=utf-8",
+			"",
+			"Episode 10 is out",
+		},
+		{
+			"Empty",
+			[]byte{},
+			"text/html",
+			"",
+			"",
+		},
+		{
+			"ContentTypeCharset",
+			iso88592,
+			"text/plain; charset=ISO-8859-2",
+			"",
+			"Žluťoučký kůň",
+		},
+		{
+			"MetaCharset",
+			windows1250,
+			"text/html",
+			"",
+			`<html><head><meta charset="windows-1250"></head><body>Ştefan cel Mare</body></html>`,
+		},
+		{
+			"Gzip",
+			compress(t, text, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+			"text/plain",
+			"gzip",
+			"Episode 10 is out",
+		},
+		{
+			"Deflate",
+			compress(t, text, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+			"text/plain",
+			"deflate",
+			"Episode 10 is out",
+		},
+		{
+			"RawDeflate",
+			compress(t, text, func(w io.Writer) io.WriteCloser {
+				writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return writer
+			}),
+			"text/plain",
+			"deflate",
+			"Episode 10 is out",
+		},
+		{
+			"Brotli",
+			compress(t, text, func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }),
+			"text/plain",
+			"br",
+			"Episode 10 is out",
+		},
+		{
+			"Pdf",
+			testPdfDocument("Episode 10 is out"),
+			"application/pdf",
+			"",
+			"Episode 10 is out",
+		},
+		{
+			"CompressedPdf",
+			compress(t, testPdfDocument("Episode 10 is out"), func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+			"application/pdf",
+			"gzip",
+			"Episode 10 is out",
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{},
+				Body:   io.NopCloser(bytes.NewReader(tv.Body)),
+			}
+			resp.Header.Set("Content-Type", tv.ContentType)
+			resp.Header.Set("Content-Encoding", tv.ContentEncoding)
+
+			reader, err := decodeResponseBody(resp, defaultMaxBodySize)
+			assert.NoError(t, err)
+			decoded, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Contains(t, string(decoded), tv.ExpectedText)
+		})
+	}
+}
+
+func Test_DecodeResponseBody_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName        string
+		Body            []byte
+		ContentType     string
+		ContentEncoding string
+	}{
+		{"UnsupportedEncoding", []byte("data"), "text/plain", "zstd"},
+		{"InvalidGzip", []byte("data"), "text/plain", "gzip"},
+		{"InvalidPdf", []byte("not a pdf"), "application/pdf", ""},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{},
+				Body:   io.NopCloser(bytes.NewReader(tv.Body)),
+			}
+			resp.Header.Set("Content-Type", tv.ContentType)
+			resp.Header.Set("Content-Encoding", tv.ContentEncoding)
+
+			_, err := decodeResponseBody(resp, defaultMaxBodySize)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"net"
+	"sort"
+	"strings"
+)
+
+// dnsRecordTypes are the record types supported by DnsCheck.
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "TXT", "MX"}
+
+// dnsCheckOptions are the options of DnsCheck.
+type dnsCheckOptions struct {
+	// Name is the name to resolve.
+	Name string `mapstructure:"name"`
+	// Type is the record type, one of dnsRecordTypes.
+	Type string `mapstructure:"type"`
+	// Expected are the expected values of the record. If empty, the values resolved on the previous run are expected.
+	Expected []string `mapstructure:"expected"`
+	// Resolver is the optional address of the resolver. The system resolver is used if it is empty.
+	Resolver     string `mapstructure:"resolver"`
+	stateOptions `mapstructure:",squash"`
+}
+
+// Validate validates the options and normalizes the record type.
+func (o *dnsCheckOptions) Validate() error {
+	if err := requireOption("name", o.Name); err != nil {
+		return err
+	}
+	o.Type = strings.ToUpper(o.Type)
+	for _, recordType := range dnsRecordTypes {
+		if o.Type == recordType {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("invalid task parameter type %s, supported types are %s", o.Type, strings.Join(dnsRecordTypes, ", ")))
+}
+
+// DnsCheck resolves a DNS record and alerts when the resolved values differ from the expected values.
+// Without expected values it alerts when the resolved values change, the values of the previous run are kept in the
+// task state by record type, name and resolver.
+var DnsCheck = task.NewFunction(
+	"Alerts when a DNS record differs from the expected values.",
+	func() *dnsCheckOptions {
+		return &dnsCheckOptions{Type: "A", stateOptions: newStateOptions()}
+	},
+	dnsCheck,
+)
+
+// dnsCheck executes the task given its decoded options.
+func dnsCheck(ctx context.Context, task *task.Task, options *dnsCheckOptions) error {
+	name, recordType := options.Name, options.Type
+
+	resolved, err := lookupRecord(ctx, newResolver(options.Resolver), recordType, name)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to resolve %s %s: %s", recordType, name, err)
+		return err
+	}
+
+	if len(options.Expected) == 0 {
+		return dnsCheckChanges(ctx, task, options, resolved)
+	}
+
+	expected := normalizeRecords(options.Expected)
+	if strings.Join(resolved, ",") != strings.Join(expected, ",") {
+		task.Alerter.PostAlert(ctx, []string{
+			fmt.Sprintf("record: %s %s", recordType, name),
+			fmt.Sprintf("expected: %s", strings.Join(expected, ",")),
+			fmt.Sprintf("resolved: %s", strings.Join(resolved, ",")),
+		})
+	}
+	return nil
+}
+
+// dnsCheckChanges alerts when the resolved values differ from the values resolved on the previous run and remembers
+// them. The first run only remembers the values.
+func dnsCheckChanges(ctx context.Context, task *task.Task, options *dnsCheckOptions, resolved []string) error {
+	store := options.store()
+	// Resolvers may answer differently, so tasks which query the same record from different resolvers keep their own
+	// values. The expected values are not part of the key, the state is only used without them.
+	stateKey := fmt.Sprintf("dns_check %s %s %s", options.Type, options.Name, options.Resolver)
+	var previous []string
+	found, err := store.Load(stateKey, &previous)
+	if err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+
+	if found && strings.Join(resolved, ",") != strings.Join(previous, ",") {
+		task.Alerter.PostAlert(ctx, []string{
+			fmt.Sprintf("record: %s %s", options.Type, options.Name),
+			fmt.Sprintf("previous: %s", strings.Join(previous, ",")),
+			fmt.Sprintf("resolved: %s", strings.Join(resolved, ",")),
+		})
+	}
+
+	if err := store.Save(stateKey, resolved); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// newResolver returns a resolver that queries the given address or the system resolver if the address is empty.
+func newResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// lookupRecord resolves the record of the given type and returns the sorted and normalized values.
+// A record that does not exist resolves to an empty list.
+func lookupRecord(ctx context.Context, resolver *net.Resolver, recordType string, name string) ([]string, error) {
+	var values = make([]string, 0, 4)
+	var err error
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+	case "CNAME":
+		var cname string
+		cname, err = resolver.LookupCNAME(ctx, name)
+		values = append(values, cname)
+	case "TXT":
+		values, err = resolver.LookupTXT(ctx, name)
+	case "MX":
+		var records []*net.MX
+		records, err = resolver.LookupMX(ctx, name)
+		for _, record := range records {
+			values = append(values, record.Host)
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("invalid task parameter type %s", recordType))
+	}
+
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) && dnsError.IsNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normalizeRecords(values), nil
+}
+
+// normalizeRecords sorts the records and strips the trailing dot from domain names.
+func normalizeRecords(records []string) []string {
+	var normalized = make([]string, 0, len(records))
+	for _, record := range records {
+		normalized = append(normalized, strings.TrimSuffix(record, "."))
+	}
+	sort.Strings(normalized)
+	return normalized
+}
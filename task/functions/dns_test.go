@@ -0,0 +1,181 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+	"hotalert/task"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startDnsServer starts a DNS server on a local UDP port which answers with the given resources.
+func startDnsServer(t *testing.T, resources []dnsmessage.Resource) string {
+	return startChangingDnsServer(t, func() []dnsmessage.Resource {
+		return resources
+	})
+}
+
+// startChangingDnsServer starts a DNS server on a local UDP port which answers with the current resources.
+func startChangingDnsServer(t *testing.T, resources func() []dnsmessage.Resource) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		var buffer = make([]byte, 512)
+		for {
+			n, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var request dnsmessage.Message
+			if err := request.Unpack(buffer[:n]); err != nil || len(request.Questions) == 0 {
+				continue
+			}
+			question := request.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: request.ID, Response: true, RecursionAvailable: true},
+				Questions: request.Questions,
+			}
+			for _, resource := range resources() {
+				if resource.Header.Name == question.Name && resource.Header.Type == question.Type {
+					response.Answers = append(response.Answers, resource)
+				}
+			}
+			if len(response.Answers) == 0 {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, address)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDnsCheckTask(t *testing.T) {
+	name := dnsmessage.MustNewName("example.test.")
+	header := func(recordType dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: recordType, Class: dnsmessage.ClassINET, TTL: 60}
+	}
+	resolver := startDnsServer(t, []dnsmessage.Resource{
+		{Header: header(dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+		{Header: header(dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+		{Header: header(dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		{Header: header(dnsmessage.TypeMX), Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx.example.test.")}},
+	})
+
+	var tests = []struct {
+		TestName       string
+		Options        task.Options
+		ExpectedAlerts int
+		ExpectedError  bool
+	}{
+		{
+			"AMatches",
+			task.Options{"name": "example.test", "resolver": resolver, "expected": []any{"10.0.0.1", "10.0.0.2"}},
+			0,
+			false,
+		},
+		{
+			"AChanged",
+			task.Options{"name": "example.test", "resolver": resolver, "expected": []any{"10.0.0.1"}},
+			1,
+			false,
+		},
+		{
+			"TxtMatches",
+			task.Options{"name": "example.test", "type": "TXT", "resolver": resolver, "expected": []any{"v=spf1 -all"}},
+			0,
+			false,
+		},
+		{
+			"MxMatches",
+			task.Options{"name": "example.test", "type": "mx", "resolver": resolver, "expected": []any{"mx.example.test."}},
+			0,
+			false,
+		},
+		{
+			"RecordRemoved",
+			task.Options{"name": "missing.test", "resolver": resolver, "expected": []any{"10.0.0.1"}},
+			1,
+			false,
+		},
+		{
+			"InvalidType",
+			task.Options{"name": "example.test", "type": "SRV", "resolver": resolver},
+			0,
+			true,
+		},
+		{
+			"MissingName",
+			task.Options{"resolver": resolver},
+			0,
+			true,
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			alerter := &recordingAlerter{}
+			err := executeTask(DnsCheck, &task.Task{
+				Options: tv.Options,
+				Timeout: 5 * time.Second,
+				Alerter: alerter,
+			})
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, alerter.Alerts(), tv.ExpectedAlerts)
+		})
+	}
+}
+
+func TestDnsCheckTask_Changes(t *testing.T) {
+	name := dnsmessage.MustNewName("example.test.")
+	header := dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60}
+	var address atomic.Value
+	address.Store([4]byte{10, 0, 0, 1})
+	resolver := startChangingDnsServer(t, func() []dnsmessage.Resource {
+		return []dnsmessage.Resource{{Header: header, Body: &dnsmessage.AResource{A: address.Load().([4]byte)}}}
+	})
+	otherResolver := startDnsServer(t, []dnsmessage.Resource{
+		{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 3}}},
+	})
+	stateDirectory := t.TempDir()
+
+	// The first run remembers the values, the record changes before the third run.
+	assert.Empty(t, runDnsChecks(t, stateDirectory, resolver, resolver))
+	address.Store([4]byte{10, 0, 0, 2})
+	assert.Equal(t, [][]string{{"record: A example.test", "previous: 10.0.0.1", "resolved: 10.0.0.2"}},
+		runDnsChecks(t, stateDirectory, resolver))
+
+	// Tasks which query different resolvers keep their own values.
+	assert.Empty(t, runDnsChecks(t, stateDirectory, otherResolver, resolver, otherResolver, resolver))
+}
+
+// runDnsChecks runs a dns_check task without expected values once with each resolver and returns the alerts.
+func runDnsChecks(t *testing.T, stateDirectory string, resolvers ...string) [][]string {
+	var alerts [][]string
+	for _, currentResolver := range resolvers {
+		alerter := &recordingAlerter{}
+		err := executeTask(DnsCheck, &task.Task{
+			Options: task.Options{
+				"name":            "example.test",
+				"resolver":        currentResolver,
+				"state_directory": stateDirectory,
+			},
+			Timeout: 5 * time.Second,
+			Alerter: alerter,
+		})
+		assert.NoError(t, err)
+		alerts = append(alerts, alerter.Alerts()...)
+	}
+	return alerts
+}
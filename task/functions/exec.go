@@ -0,0 +1,141 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// execOptions are the options of Exec.
+type execOptions struct {
+	// Command is the command, a string which is executed with the shell or a list which is executed directly.
+	Command any `mapstructure:"command"`
+	// ExitCodes are the expected exit codes.
+	ExitCodes []int `mapstructure:"exit_codes"`
+	// Keywords are the keywords searched in the standard output.
+	Keywords []string `mapstructure:"keywords"`
+	// Regex is the optional regular expression matched against the standard output.
+	Regex string `mapstructure:"regex"`
+	// arguments are the arguments of the command built from Command.
+	arguments []string
+	// regex is the compiled Regex.
+	regex *regexp.Regexp
+}
+
+// Validate validates the options, builds the command arguments and compiles the regex.
+func (o *execOptions) Validate() error {
+	var err error
+	o.arguments, err = commandArguments(o.Command)
+	if err != nil {
+		return err
+	}
+	if o.Regex != "" {
+		o.regex, err = regexp.Compile(o.Regex)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid task parameter regex %s: %s", o.Regex, err))
+		}
+	}
+	return nil
+}
+
+// Exec runs a local command and alerts when the exit code is not one of the expected exit codes,
+// when the standard output contains any of the keywords or when it matches the regex.
+var Exec = task.NewFunction(
+	"Runs a local command and alerts on unexpected exit codes or output.",
+	func() *execOptions {
+		return &execOptions{ExitCodes: []int{0}}
+	},
+	execCommand,
+)
+
+// execCommand executes the task given its decoded options.
+func execCommand(ctx context.Context, task *task.Task, options *execOptions) error {
+	command := options.arguments
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		logging.SugaredLogger.Errorf("Command %v timed out: %s", command, ctx.Err())
+		return ctx.Err()
+	}
+	exitCode := 0
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		exitCode = exitError.ExitCode()
+	} else if err != nil {
+		logging.SugaredLogger.Errorf("Failed to run command %v: %s", command, err)
+		return err
+	}
+
+	// Check the conditions and save the ones that fired.
+	output := stdout.String()
+	setOutput(ctx, "stdout", strings.TrimSpace(output))
+	setOutput(ctx, "exit_code", strconv.Itoa(exitCode))
+	var reasons = make([]string, 0, 4)
+	if !containsInt(options.ExitCodes, exitCode) {
+		reasons = append(reasons, fmt.Sprintf("exit code: %d", exitCode))
+	}
+	for _, keyword := range options.Keywords {
+		if strings.Contains(output, keyword) {
+			reasons = append(reasons, keyword)
+		}
+	}
+	if options.regex != nil {
+		for _, match := range options.regex.FindAllString(output, 10) {
+			reasons = append(reasons, match)
+		}
+	}
+
+	// If we have reasons post an alert.
+	if len(reasons) > 0 {
+		task.Alerter.PostAlert(ctx, reasons)
+	}
+	return nil
+}
+
+// commandArguments returns the command option as a list of arguments.
+// A string command is executed with the shell, a list command is executed directly.
+func commandArguments(command any) ([]string, error) {
+	switch typedCommand := command.(type) {
+	case string:
+		if typedCommand != "" {
+			return []string{"sh", "-c", typedCommand}, nil
+		}
+	case []any:
+		var arguments = make([]string, 0, len(typedCommand))
+		for _, rawArgument := range typedCommand {
+			argument, ok := rawArgument.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("invalid task parameter command %v, arguments must be strings", command))
+			}
+			arguments = append(arguments, argument)
+		}
+		if len(arguments) > 0 {
+			return arguments, nil
+		}
+	case []string:
+		if len(typedCommand) > 0 {
+			return typedCommand, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("invalid task parameter command %v", command))
+}
+
+// containsInt returns true if values contains value.
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package functions
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"testing"
+	"time"
+)
+
+func TestExecTask(t *testing.T) {
+	var tests = []struct {
+		TestName       string
+		Options        task.Options
+		Timeout        time.Duration
+		ExpectedAlerts [][]string
+		ExpectedError  bool
+	}{
+		{
+			"Success",
+			task.Options{"command": []any{"true"}},
+			10 * time.Second,
+			nil,
+			false,
+		},
+		{
+			"UnexpectedExitCode",
+			task.Options{"command": "exit 3"},
+			10 * time.Second,
+			[][]string{{"exit code: 3"}},
+			false,
+		},
+		{
+			"ExpectedExitCode",
+			task.Options{"command": "exit 3", "exit_codes": []any{0, 3}},
+			10 * time.Second,
+			nil,
+			false,
+		},
+		{
+			"KeywordMatches",
+			task.Options{"command": []any{"echo", "nginx: inactive"}, "keywords": []any{"inactive", "failed"}},
+			10 * time.Second,
+			[][]string{{"inactive"}},
+			false,
+		},
+		{
+			"RegexMatches",
+			task.Options{"command": "echo /dev/sda1 91%", "regex": "(9[0-9]|100)%"},
+			10 * time.Second,
+			[][]string{{"91%"}},
+			false,
+		},
+		{
+			"RegexDoesNotMatch",
+			task.Options{"command": "echo /dev/sda1 42%", "regex": "(9[0-9]|100)%"},
+			10 * time.Second,
+			nil,
+			false,
+		},
+		{
+			"InvalidRegex",
+			task.Options{"command": "true", "regex": "("},
+			10 * time.Second,
+			nil,
+			true,
+		},
+		{
+			"MissingCommand",
+			task.Options{},
+			10 * time.Second,
+			nil,
+			true,
+		},
+		{
+			"CommandNotFound",
+			task.Options{"command": []any{"hotalert-command-not-found"}},
+			10 * time.Second,
+			nil,
+			true,
+		},
+		{
+			"Timeout",
+			task.Options{"command": []any{"sleep", "5"}},
+			100 * time.Millisecond,
+			nil,
+			true,
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			alerter := &recordingAlerter{}
+			err := executeTask(Exec, &task.Task{
+				Options: tv.Options,
+				Timeout: tv.Timeout,
+				Alerter: alerter,
+			})
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tv.ExpectedAlerts, alerter.Alerts())
+		})
+	}
+}
+
+func TestExecTask_Outputs(t *testing.T) {
+	outputs := task.NewOutputs()
+	ctx, cancel := context.WithTimeout(task.ContextWithOutputs(context.Background(), outputs), 10*time.Second)
+	defer cancel()
+
+	err := Exec.Execute(ctx, &task.Task{
+		Options: task.Options{"command": "echo 42; exit 3", "exit_codes": []any{3}},
+		Alerter: &recordingAlerter{},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"stdout": "42", "exit_code": "3"}, outputs.Values())
+}
@@ -0,0 +1,193 @@
+package functions
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// fileWatchFingerprintSize is the number of bytes from the beginning of the file used to detect rotation.
+const fileWatchFingerprintSize = 256
+
+// fileWatchMaxAlertLines is the maximum number of matching lines included in an alert.
+const fileWatchMaxAlertLines = 20
+
+// fileWatchState is the state kept between runs of the file_watch task.
+type fileWatchState struct {
+	// Offset is the offset up to which the file was read.
+	Offset int64 `json:"offset"`
+	// Fingerprint is the hash of the beginning of the file, which changes when the file is rotated.
+	Fingerprint string `json:"fingerprint"`
+	// FingerprintSize is the number of bytes hashed in Fingerprint.
+	FingerprintSize int `json:"fingerprint_size"`
+}
+
+// fileWatchOptions are the options of FileWatch.
+type fileWatchOptions struct {
+	// Path is the path of the watched file.
+	Path string `mapstructure:"path"`
+	// Keywords are the keywords searched in the appended lines.
+	Keywords []string `mapstructure:"keywords"`
+	// Regex is the optional regular expression matched against the appended lines.
+	Regex string `mapstructure:"regex"`
+	// FromBeginning controls whether the file is read from the beginning on the first run.
+	FromBeginning bool `mapstructure:"from_beginning"`
+	stateOptions  `mapstructure:",squash"`
+	// regex is the compiled Regex.
+	regex *regexp.Regexp
+}
+
+// Validate validates the options and compiles the regex.
+func (o *fileWatchOptions) Validate() error {
+	if err := requireOption("path", o.Path); err != nil {
+		return err
+	}
+	if o.Regex != "" {
+		var err error
+		o.regex, err = regexp.Compile(o.Regex)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid task parameter regex %s: %s", o.Regex, err))
+		}
+	}
+	return nil
+}
+
+// FileWatch reads the lines appended to a file since the previous run and alerts with the lines which contain
+// any of the keywords or match the regex. The file offset is kept in the task state, by path, keywords and regex.
+// When the file was rotated or truncated since the previous run it is read from the beginning.
+var FileWatch = task.NewFunction(
+	"Alerts on lines appended to a file which contain keywords or match a regex.",
+	func() *fileWatchOptions {
+		return &fileWatchOptions{stateOptions: newStateOptions()}
+	},
+	fileWatch,
+)
+
+// fileWatch executes the task given its decoded options.
+func fileWatch(ctx context.Context, task *task.Task, options *fileWatchOptions) error {
+	path, store := options.Path, options.store()
+
+	file, err := os.Open(path)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to open file %s: %s", path, err)
+		return err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to stat file %s: %s", path, err)
+		return err
+	}
+
+	// Figure out where to continue reading from. Tasks which watch the same file for different lines keep their own
+	// offset.
+	stateKey := fmt.Sprintf("file_watch %s %q %s", path, options.Keywords, options.Regex)
+	var previousState fileWatchState
+	found, err := store.Load(stateKey, &previousState)
+	if err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	previousFingerprint, _, err := fileFingerprint(file, previousState.FingerprintSize)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to read file %s: %s", path, err)
+		return err
+	}
+	fingerprint, fingerprintSize, err := fileFingerprint(file, fileWatchFingerprintSize)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to read file %s: %s", path, err)
+		return err
+	}
+	var offset int64
+	switch {
+	case !found && !options.FromBeginning:
+		offset = fileInfo.Size()
+	case !found:
+		offset = 0
+	case previousState.Offset > fileInfo.Size():
+		logging.SugaredLogger.Infof("File %s was truncated, reading from the beginning", path)
+		offset = 0
+	case previousFingerprint != previousState.Fingerprint:
+		logging.SugaredLogger.Infof("File %s was rotated, reading from the beginning", path)
+		offset = 0
+	default:
+		offset = previousState.Offset
+	}
+
+	// Read the complete lines appended since the previous run and save the matching ones.
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		logging.SugaredLogger.Errorf("Failed to seek file %s: %s", path, err)
+		return err
+	}
+	var matchedLines = make([]string, 0, 10)
+	var matchedLinesCount = 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// Partial lines are read again on the next run once they are complete.
+			break
+		}
+		if err != nil {
+			logging.SugaredLogger.Errorf("Failed to read file %s: %s", path, err)
+			return err
+		}
+		offset += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if lineMatches(line, options.Keywords, options.regex) {
+			matchedLinesCount += 1
+			if len(matchedLines) < fileWatchMaxAlertLines {
+				matchedLines = append(matchedLines, line)
+			}
+		}
+	}
+
+	// If we have matched lines post an alert.
+	if matchedLinesCount > len(matchedLines) {
+		matchedLines = append(matchedLines, fmt.Sprintf("and %d more lines", matchedLinesCount-len(matchedLines)))
+	}
+	if len(matchedLines) > 0 {
+		task.Alerter.PostAlert(ctx, matchedLines)
+	}
+
+	if err := store.Save(stateKey, fileWatchState{
+		Offset:          offset,
+		Fingerprint:     fingerprint,
+		FingerprintSize: fingerprintSize,
+	}); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// fileFingerprint returns the hash of the first size bytes of the file and the number of bytes hashed,
+// which is smaller than size when the file is shorter.
+func fileFingerprint(file *os.File, size int) (string, int, error) {
+	var buffer = make([]byte, size)
+	n, err := file.ReadAt(buffer, 0)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	hash := sha1.Sum(buffer[:n])
+	return hex.EncodeToString(hash[:]), n, nil
+}
+
+// lineMatches returns true if the line contains any of the keywords or matches the regex.
+func lineMatches(line string, keywords []string, regex *regexp.Regexp) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(line, keyword) {
+			return true
+		}
+	}
+	return regex != nil && regex.MatchString(line)
+}
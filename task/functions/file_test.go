@@ -0,0 +1,140 @@
+package functions
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendToFile(t *testing.T, path string, contents string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = file.WriteString(contents)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func TestFileWatchTask(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "syslog")
+	appendToFile(t, logPath, "kernel: error before the first run\n")
+
+	alerter := &recordingAlerter{}
+	currentTask := &task.Task{
+		Options: task.Options{
+			"path":            logPath,
+			"keywords":        []any{"error"},
+			"regex":           "temperature [89][0-9]C",
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	}
+
+	// First run starts at the end of the file.
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Len(t, alerter.Alerts(), 0)
+
+	// Appended lines are matched, partial lines are left for the next run.
+	appendToFile(t, logPath, "sshd: accepted\nkernel: error on disk\nsensor: temperature 85C\nkernel: err")
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Equal(t, [][]string{{"kernel: error on disk", "sensor: temperature 85C"}}, alerter.Alerts())
+
+	appendToFile(t, logPath, "or completed\n")
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Equal(t, []string{"kernel: error completed"}, alerter.Alerts()[1])
+
+	// Nothing new was appended.
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Len(t, alerter.Alerts(), 2)
+
+	// Truncated files are read from the beginning.
+	assert.NoError(t, os.WriteFile(logPath, []byte("error after truncate\n"), 0600))
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Equal(t, []string{"error after truncate"}, alerter.Alerts()[2])
+
+	// Rotated files are read from the beginning, even when they are larger than the previous offset.
+	assert.NoError(t, os.Remove(logPath))
+	appendToFile(t, logPath, "a new file was created with an error in a long first line\n")
+	assert.NoError(t, executeTask(FileWatch, currentTask))
+	assert.Equal(t, []string{"a new file was created with an error in a long first line"}, alerter.Alerts()[3])
+}
+
+func TestFileWatchTask_SameFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "syslog")
+	appendToFile(t, logPath, "kernel: started\n")
+	stateDirectory := t.TempDir()
+
+	newTask := func(keyword string, alerter *recordingAlerter) *task.Task {
+		return &task.Task{
+			Options: task.Options{
+				"path":            logPath,
+				"keywords":        []any{keyword},
+				"state_directory": stateDirectory,
+			},
+			Timeout: 10 * time.Second,
+			Alerter: alerter,
+		}
+	}
+	errorAlerter, oomAlerter := &recordingAlerter{}, &recordingAlerter{}
+	errorTask, oomTask := newTask("error", errorAlerter), newTask("OOM", oomAlerter)
+	assert.NoError(t, executeTask(FileWatch, errorTask))
+	assert.NoError(t, executeTask(FileWatch, oomTask))
+
+	// Each task keeps its own offset, so both see the appended lines.
+	appendToFile(t, logPath, "kernel: error on disk\nkernel: OOM killer invoked\n")
+	assert.NoError(t, executeTask(FileWatch, errorTask))
+	assert.NoError(t, executeTask(FileWatch, oomTask))
+	assert.Equal(t, [][]string{{"kernel: error on disk"}}, errorAlerter.Alerts())
+	assert.Equal(t, [][]string{{"kernel: OOM killer invoked"}}, oomAlerter.Alerts())
+}
+
+func TestFileWatchTask_MaxAlertLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "syslog")
+	for i := 0; i < fileWatchMaxAlertLines+5; i++ {
+		appendToFile(t, logPath, fmt.Sprintf("error %d\n", i))
+	}
+
+	alerter := &recordingAlerter{}
+	err := executeTask(FileWatch, &task.Task{
+		Options: task.Options{
+			"path":            logPath,
+			"keywords":        []any{"error"},
+			"from_beginning":  true,
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, alerter.Alerts(), 1)
+	assert.Len(t, alerter.Alerts()[0], fileWatchMaxAlertLines+1)
+	assert.Equal(t, "and 5 more lines", alerter.Alerts()[0][fileWatchMaxAlertLines])
+}
+
+func TestFileWatchTask_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Options  task.Options
+	}{
+		{"MissingPath", task.Options{}},
+		{"FileDoesNotExist", task.Options{"path": filepath.Join(t.TempDir(), "missing")}},
+		{"InvalidRegex", task.Options{"path": "/dev/null", "regex": "("}},
+		{"InvalidFromBeginning", task.Options{"path": "/dev/null", "from_beginning": "yes"}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			tv.Options["state_directory"] = t.TempDir()
+			err := executeTask(FileWatch, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: &recordingAlerter{},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
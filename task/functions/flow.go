@@ -0,0 +1,271 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// flowVariablePattern matches the ${name} and ${env:NAME} placeholders in step options, and the escaped $${...} ones.
+var flowVariablePattern = regexp.MustCompile(`\$?\$\{([^}]+)}`)
+
+// flowExtraction describes how a variable is extracted from the response of a step.
+type flowExtraction struct {
+	// Name is the name of the variable.
+	Name string
+	// Regex is the regular expression which extracts the value. The first group is used if it has groups.
+	Regex *regexp.Regexp
+	// Selector is the CSS selector of the element which holds the value.
+	Selector *cssSelector
+	// Attribute is the attribute of the selected element which holds the value. The text is used if it is empty.
+	Attribute string
+}
+
+// flowStepOptions are the options of a step of HttpFlow.
+type flowStepOptions struct {
+	// Url is the url of the request.
+	Url                string `mapstructure:"url"`
+	httpRequestOptions `mapstructure:",squash"`
+	// Extract maps variable names to the extractions of their values from the response.
+	Extract map[string]flowExtractionOptions `mapstructure:"extract"`
+}
+
+// flowExtractionOptions are the options of an extraction, which use either a regex or a selector.
+type flowExtractionOptions struct {
+	Regex     string `mapstructure:"regex"`
+	Selector  string `mapstructure:"selector"`
+	Attribute string `mapstructure:"attribute"`
+}
+
+// httpFlowOptions are the options of HttpFlow.
+type httpFlowOptions struct {
+	// Steps are the steps, which are decoded into flowStepOptions after their variables are expanded.
+	Steps          []map[string]any `mapstructure:"steps"`
+	keywordOptions `mapstructure:",squash"`
+	// MaxBodySize is the maximum number of response bytes read for each step.
+	MaxBodySize int `mapstructure:"max_body_size"`
+}
+
+// Validate validates the options and the steps.
+func (o *httpFlowOptions) Validate() error {
+	if len(o.Steps) == 0 {
+		return errors.New("invalid task parameter steps, steps are required")
+	}
+	for i, stepMap := range o.Steps {
+		if _, _, err := parseFlowStep(stepMap); err != nil {
+			return errors.New(fmt.Sprintf("invalid step %d: %s", i, err))
+		}
+	}
+	if o.MaxBodySize <= 0 {
+		return errors.New(fmt.Sprintf("invalid task parameter max_body_size %d, must be positive", o.MaxBodySize))
+	}
+	return o.keywordOptions.Validate()
+}
+
+// HttpFlow executes an ordered list of http requests, the steps, which share a cookie jar. Values extracted from
+// the response of a step, such as CSRF tokens, can be used in the following steps as ${name} and environment variables
+// can be used as ${env:NAME}, which keeps secrets out of the workload file. The response of the last step is matched
+// against the keywords like in WebScrape.
+var HttpFlow task.Function = flowFunction{Function: task.NewFunction(
+	"Executes a sequence of http requests sharing cookies and alerts when keywords are found in the last response.",
+	func() *httpFlowOptions {
+		return &httpFlowOptions{keywordOptions: newKeywordOptions(), MaxBodySize: defaultMaxBodySize}
+	},
+	httpFlow,
+)}
+
+// flowFunction is the Function of HttpFlow, which escapes the outputs of other tasks inserted in its steps.
+type flowFunction struct {
+	task.Function
+}
+
+// EscapeOutput escapes the placeholders in the output of another task as $${...}, so that an output which came from a
+// remote page cannot read environment variables, which would send them to a url that the page controls.
+func (flowFunction) EscapeOutput(value string) string {
+	return strings.Replace(value, "${", "$${", -1)
+}
+
+// httpFlow executes the task given its decoded options.
+func httpFlow(ctx context.Context, task *task.Task, options *httpFlowOptions) error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	baseClient := *httpClient(task)
+	baseClient.Jar = jar
+
+	var variables = make(map[string]string)
+	var lastBody []byte
+	for i, stepMap := range options.Steps {
+		lastBody, err = executeFlowStep(ctx, &baseClient, stepMap, variables, int64(options.MaxBodySize))
+		if err != nil {
+			logging.SugaredLogger.Errorf("Failed to execute step %d: %s", i, err)
+			return errors.New(fmt.Sprintf("step %d: %s", i, err))
+		}
+	}
+	for name, value := range variables {
+		setOutput(ctx, name, value)
+	}
+
+	// Search for matched keywords in the last response.
+	keywordMatch := options.keywordMatch
+	matchedKeywords, truncated, err := searchKeywords(bytes.NewReader(lastBody), keywordMatch.SearchedKeywords(), int64(options.MaxBodySize))
+	if err != nil {
+		return err
+	}
+	if alertContext := keywordMatch.Evaluate(matchedKeywords, truncated); alertContext != nil {
+		task.Alerter.PostAlert(ctx, alertContext)
+	}
+	return nil
+}
+
+// executeFlowStep executes a step and extracts its variables. It returns the decoded response body.
+func executeFlowStep(ctx context.Context, baseClient *http.Client, stepMap map[string]any, variables map[string]string, maxBodySize int64) ([]byte, error) {
+	expanded, err := expandFlowVariables(stepMap, variables)
+	if err != nil {
+		return nil, err
+	}
+	stepOptions, extractions, err := parseFlowStep(expanded.(map[string]any))
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := fetchPage(ctx, stepOptions.client(baseClient), &stepOptions.httpRequestOptions, stepOptions.Url, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	for _, extraction := range extractions {
+		value, err := extraction.extract(body)
+		if err != nil {
+			return nil, err
+		}
+		variables[extraction.Name] = value
+	}
+	return body, nil
+}
+
+// parseFlowStep decodes and validates the options of a step and its extractions.
+func parseFlowStep(stepMap map[string]any) (*flowStepOptions, []flowExtraction, error) {
+	stepOptions := flowStepOptions{httpRequestOptions: newHttpRequestOptions()}
+	if err := task.DecodeOptions(stepMap, &stepOptions); err != nil {
+		return nil, nil, err
+	}
+	if err := requireOption("url", stepOptions.Url); err != nil {
+		return nil, nil, err
+	}
+	if err := stepOptions.httpRequestOptions.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var extractions = make([]flowExtraction, 0, len(stepOptions.Extract))
+	for name, extractionOptions := range stepOptions.Extract {
+		extraction := flowExtraction{
+			Name:      name,
+			Attribute: extractionOptions.Attribute,
+		}
+		if extractionOptions.Selector != "" {
+			var err error
+			extraction.Selector, err = parseSelector(extractionOptions.Selector)
+			if err != nil {
+				return nil, nil, errors.New(fmt.Sprintf("invalid selector for %s: %s", name, err))
+			}
+		}
+		if extractionOptions.Regex != "" {
+			var err error
+			extraction.Regex, err = regexp.Compile(extractionOptions.Regex)
+			if err != nil {
+				return nil, nil, errors.New(fmt.Sprintf("invalid regex for %s: %s", name, err))
+			}
+		}
+		if (extraction.Regex == nil) == (extraction.Selector == nil) {
+			return nil, nil, errors.New(fmt.Sprintf("extract %s needs either a regex or a selector", name))
+		}
+		extractions = append(extractions, extraction)
+	}
+	return &stepOptions, extractions, nil
+}
+
+// extract returns the value of the extraction from the body.
+func (e *flowExtraction) extract(body []byte) (string, error) {
+	if e.Regex != nil {
+		match := e.Regex.FindSubmatch(body)
+		if match == nil {
+			return "", errors.New(fmt.Sprintf("no match for %s", e.Name))
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+	value, found, err := selectValue(bytes.NewReader(body), e.Selector, e.Attribute)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New(fmt.Sprintf("no element matches %s for %s", e.Selector, e.Name))
+	}
+	return value, nil
+}
+
+// expandFlowVariables replaces the ${name} and ${env:NAME} placeholders in all the strings of the value, the escaped
+// $${...} placeholders are replaced with ${...}. The extract option is not expanded, since its regexes may contain the
+// same syntax.
+func expandFlowVariables(value any, variables map[string]string) (any, error) {
+	switch typedValue := value.(type) {
+	case string:
+		var expandErr error
+		expanded := flowVariablePattern.ReplaceAllStringFunc(typedValue, func(placeholder string) string {
+			if strings.HasPrefix(placeholder, "$$") {
+				return placeholder[1:]
+			}
+			name := placeholder[2 : len(placeholder)-1]
+			if strings.HasPrefix(name, "env:") {
+				envName := strings.TrimPrefix(name, "env:")
+				envValue, found := os.LookupEnv(envName)
+				if !found {
+					expandErr = errors.New(fmt.Sprintf("environment variable %s is not set", envName))
+				}
+				return envValue
+			}
+			variableValue, found := variables[name]
+			if !found {
+				expandErr = errors.New(fmt.Sprintf("variable %s is not defined by a previous step", name))
+			}
+			return variableValue
+		})
+		return expanded, expandErr
+	case map[string]any:
+		var expanded = make(map[string]any, len(typedValue))
+		for key, item := range typedValue {
+			if key == "extract" {
+				expanded[key] = item
+				continue
+			}
+			expandedItem, err := expandFlowVariables(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = expandedItem
+		}
+		return expanded, nil
+	case []any:
+		var expanded = make([]any, 0, len(typedValue))
+		for _, item := range typedValue {
+			expandedItem, err := expandFlowVariables(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, expandedItem)
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+package functions
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestPortal returns a test server which requires logging in with a CSRF token before showing the orders page.
+func newTestPortal() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodGet {
+			http.SetCookie(writer, &http.Cookie{Name: "csrf", Value: "token-123"})
+			_, _ = writer.Write([]byte(`<form><input type="hidden" name="csrf_token" value="token-123"></form>`))
+			return
+		}
+		csrfCookie, err := request.Cookie("csrf")
+		if err != nil || csrfCookie.Value != request.FormValue("csrf_token") ||
+			request.FormValue("user") != "admin" || request.FormValue("password") != "secret" {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.SetCookie(writer, &http.Cookie{Name: "session", Value: "logged-in"})
+		http.Redirect(writer, request, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`Welcome, your account id is <b id="account">42</b>`))
+	})
+	mux.HandleFunc("/orders", func(writer http.ResponseWriter, request *http.Request) {
+		if _, err := request.Cookie("session"); err != nil {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = writer.Write([]byte(fmt.Sprintf("Orders of %s: Shipped", request.URL.Query().Get("account"))))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHttpFlowTask(t *testing.T) {
+	portal := newTestPortal()
+	defer portal.Close()
+	t.Setenv("HOTALERT_TEST_USER", "admin")
+	t.Setenv("HOTALERT_TEST_PASSWORD", "secret")
+
+	alerter := &recordingAlerter{}
+	err := executeTask(HttpFlow, &task.Task{
+		Options: task.Options{
+			"steps": []any{
+				map[string]any{
+					"url": portal.URL + "/login",
+					"extract": map[string]any{
+						"csrf": map[string]any{"selector": "input[name=csrf_token]", "attribute": "value"},
+					},
+				},
+				map[string]any{
+					"url":     portal.URL + "/login",
+					"method":  "POST",
+					"headers": map[string]any{"Content-Type": "application/x-www-form-urlencoded"},
+					"body":    "user=${env:HOTALERT_TEST_USER}&password=${env:HOTALERT_TEST_PASSWORD}&csrf_token=${csrf}",
+					"extract": map[string]any{
+						"account": map[string]any{"regex": `account id is <b id="account">(\d+)</b>`},
+					},
+				},
+				map[string]any{
+					"url":   portal.URL + "/orders",
+					"query": map[string]any{"account": "${account}"},
+				},
+			},
+			"keywords": []any{"Orders of 42: Shipped"},
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Orders of 42: Shipped"}}, alerter.Alerts())
+}
+
+func TestHttpFlowTask_Errors(t *testing.T) {
+	portal := newTestPortal()
+	defer portal.Close()
+
+	var tests = []struct {
+		TestName string
+		Steps    any
+	}{
+		{"MissingSteps", nil},
+		{"StepIsNotAMap", []any{"login"}},
+		{"NotLoggedIn", []any{map[string]any{"url": portal.URL + "/orders"}}},
+		{"MissingEnvironmentVariable", []any{map[string]any{"url": portal.URL + "/login?user=${env:HOTALERT_TEST_MISSING}"}}},
+		{"UndefinedVariable", []any{map[string]any{"url": portal.URL + "/login?token=${csrf}"}}},
+		{"ExtractionDoesNotMatch", []any{map[string]any{
+			"url":     portal.URL + "/login",
+			"extract": map[string]any{"csrf": map[string]any{"regex": "token=([a-z]+)"}},
+		}}},
+		{"InvalidSelector", []any{map[string]any{
+			"url":     portal.URL + "/login",
+			"extract": map[string]any{"csrf": map[string]any{"selector": "input[name=csrf]x"}},
+		}}},
+		{"InvalidExtraction", []any{map[string]any{
+			"url":     portal.URL + "/login",
+			"extract": map[string]any{"csrf": map[string]any{"attribute": "value"}},
+		}}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			err := executeTask(HttpFlow, &task.Task{
+				Options: task.Options{
+					"steps":    tv.Steps,
+					"keywords": []any{"Shipped"},
+				},
+				Timeout: 10 * time.Second,
+				Alerter: &recordingAlerter{},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
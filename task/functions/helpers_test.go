@@ -0,0 +1,13 @@
+package functions
+
+import (
+	"context"
+	"hotalert/task"
+)
+
+// executeTask executes the task with a context which times out after the task timeout, like the executor does.
+func executeTask(function task.Function, currentTask *task.Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), currentTask.Timeout)
+	defer cancel()
+	return function.Execute(ctx, currentTask)
+}
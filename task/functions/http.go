@@ -0,0 +1,163 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/hostlimit"
+	"hotalert/task"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultUserAgent is the User-Agent sent when the task does not configure one.
+const defaultUserAgent = "Mozilla/5.0 (compatible; hotalert)"
+
+// httpRequestOptions are the options used to build the http request of a task.
+type httpRequestOptions struct {
+	// Method is the http method.
+	Method string `mapstructure:"method"`
+	// Headers are the headers sent with the request.
+	Headers map[string]string `mapstructure:"headers"`
+	// Cookies are the cookies sent with the request.
+	Cookies map[string]string `mapstructure:"cookies"`
+	// Query are the query parameters added to the url.
+	Query map[string]string `mapstructure:"query"`
+	// Body is the request body.
+	Body string `mapstructure:"body"`
+	// BasicAuth holds the username and password for basic authentication.
+	BasicAuth map[string]string `mapstructure:"basic_auth"`
+	// BearerToken is the token for bearer authentication.
+	BearerToken string `mapstructure:"bearer_token"`
+	// FollowRedirects controls whether redirects are followed.
+	FollowRedirects bool `mapstructure:"follow_redirects"`
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int `mapstructure:"max_redirects"`
+	// StatusCodes are the accepted response status codes.
+	StatusCodes []int `mapstructure:"status_codes"`
+	// RobotsTxt controls whether requests disallowed by the robots.txt file of the host are refused.
+	RobotsTxt bool `mapstructure:"robots_txt"`
+}
+
+// newHttpRequestOptions returns httpRequestOptions with the default values.
+func newHttpRequestOptions() httpRequestOptions {
+	return httpRequestOptions{
+		Method:          http.MethodGet,
+		FollowRedirects: true,
+		MaxRedirects:    10,
+		StatusCodes:     []int{http.StatusOK},
+	}
+}
+
+// Validate validates the options and normalizes the method.
+func (o *httpRequestOptions) Validate() error {
+	o.Method = strings.ToUpper(o.Method)
+	if o.BasicAuth != nil && o.BasicAuth["username"] == "" {
+		return errors.New("invalid task parameter basic_auth, username is missing")
+	}
+	return nil
+}
+
+// newRequest builds the http request for the given url.
+func (o *httpRequestOptions) newRequest(ctx context.Context, targetUrl string) (*http.Request, error) {
+	parsedUrl, err := url.Parse(targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Query) > 0 {
+		query := parsedUrl.Query()
+		for key, value := range o.Query {
+			query.Set(key, value)
+		}
+		parsedUrl.RawQuery = query.Encode()
+	}
+
+	var body io.Reader
+	if o.Body != "" {
+		body = strings.NewReader(o.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, o.Method, parsedUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for key, value := range o.Headers {
+		req.Header.Set(key, value)
+	}
+	for name, value := range o.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	if o.BasicAuth != nil {
+		req.SetBasicAuth(o.BasicAuth["username"], o.BasicAuth["password"])
+	}
+	if o.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	return req, nil
+}
+
+// httpClient returns the http client of the task or http.DefaultClient if the task has none.
+func httpClient(task *task.Task) *http.Client {
+	if task.HttpClient != nil {
+		return task.HttpClient
+	}
+	return http.DefaultClient
+}
+
+// client returns a copy of the base client which applies the redirect policy and the host limits of the executor.
+func (o *httpRequestOptions) client(base *http.Client) *http.Client {
+	client := *hostlimit.Client(base)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !o.FollowRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= o.MaxRedirects {
+			return errors.New(fmt.Sprintf("stopped after %d redirects", o.MaxRedirects))
+		}
+		return nil
+	}
+	return &client
+}
+
+// acceptsStatusCode returns true if the status code is one of the accepted status codes.
+func (o *httpRequestOptions) acceptsStatusCode(statusCode int) bool {
+	return containsInt(o.StatusCodes, statusCode)
+}
+
+// statusCodeError returns the error for a response with an unexpected status code, which the executor may retry.
+func statusCodeError(message string, statusCode int) error {
+	return &task.StatusCodeError{Message: message, StatusCode: statusCode}
+}
+
+// fetchPage requests the page with the request options and returns the decoded response body and the final response,
+// whose body is already closed. At most maxBodySize bytes of the body are read.
+func fetchPage(ctx context.Context, client *http.Client, requestOptions *httpRequestOptions, pageUrl string, maxBodySize int64) ([]byte, *http.Response, error) {
+	if err := requestOptions.checkRobots(ctx, client, pageUrl); err != nil {
+		return nil, nil, err
+	}
+	req, err := requestOptions.newRequest(ctx, pageUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if !requestOptions.acceptsStatusCode(resp.StatusCode) {
+		return nil, nil, statusCodeError("failed to query page", resp.StatusCode)
+	}
+	decodedBody, err := decodeResponseBody(resp, maxBodySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := io.ReadAll(io.LimitReader(decodedBody, maxBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, resp, nil
+}
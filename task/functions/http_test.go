@@ -0,0 +1,152 @@
+package functions
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeHttpRequestOptions decodes and validates the http request options.
+func decodeHttpRequestOptions(t *testing.T, options task.Options) *httpRequestOptions {
+	requestOptions := newHttpRequestOptions()
+	assert.NoError(t, task.DecodeOptions(options, &requestOptions))
+	assert.NoError(t, requestOptions.Validate())
+	return &requestOptions
+}
+
+func Test_HttpRequestOptions_Defaults(t *testing.T) {
+	requestOptions := decodeHttpRequestOptions(t, task.Options{})
+	assert.Equal(t, &httpRequestOptions{
+		Method:          http.MethodGet,
+		FollowRedirects: true,
+		MaxRedirects:    10,
+		StatusCodes:     []int{http.StatusOK},
+	}, requestOptions)
+}
+
+func Test_HttpRequestOptions_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Options  task.Options
+	}{
+		{"InvalidMethod", task.Options{"method": 1}},
+		{"InvalidHeaders", task.Options{"headers": []any{"User-Agent"}}},
+		{"InvalidBasicAuth", task.Options{"basic_auth": map[string]any{"password": "secret"}}},
+		{"InvalidFollowRedirects", task.Options{"follow_redirects": "no"}},
+		{"InvalidStatusCodes", task.Options{"status_codes": 200}},
+		{"UnknownOption", task.Options{"methd": "POST"}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			tv.Options["url"] = "https://example.com"
+			tv.Options["keywords"] = []any{"keyword"}
+			assert.Error(t, WebScrape.ValidateOptions(tv.Options))
+		})
+	}
+}
+
+func Test_HttpRequestOptions_NewRequest(t *testing.T) {
+	requestOptions := decodeHttpRequestOptions(t, task.Options{
+		"method":       "post",
+		"headers":      map[string]any{"Accept-Language": "ro-RO", "User-Agent": "Firefox"},
+		"cookies":      map[string]any{"session": "abc"},
+		"query":        map[string]any{"page": 2},
+		"body":         "q=jobs",
+		"basic_auth":   map[string]any{"username": "user", "password": "secret"},
+		"bearer_token": "token",
+	})
+
+	req, err := requestOptions.newRequest(context.Background(), "https://example.com/search?sort=new")
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://example.com/search?page=2&sort=new", req.URL.String())
+	assert.Equal(t, "ro-RO", req.Header.Get("Accept-Language"))
+	assert.Equal(t, "Firefox", req.Header.Get("User-Agent"))
+	assert.Equal(t, "session=abc", req.Header.Get("Cookie"))
+	// Bearer authentication is set last and overrides basic authentication.
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "q=jobs", string(body))
+}
+
+func Test_HttpRequestOptions_DefaultUserAgent(t *testing.T) {
+	requestOptions := decodeHttpRequestOptions(t, task.Options{})
+	req, err := requestOptions.newRequest(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultUserAgent, req.Header.Get("User-Agent"))
+	assert.Nil(t, req.Body)
+}
+
+func Test_HttpRequestOptions_Client(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/final" {
+			_, _ = writer.Write([]byte("final"))
+			return
+		}
+		http.Redirect(writer, request, "/final", http.StatusFound)
+	}))
+	defer testHttpServer.Close()
+
+	var tests = []struct {
+		TestName           string
+		Options            task.Options
+		ExpectedStatusCode int
+		ExpectedError      bool
+	}{
+		{"FollowRedirects", task.Options{}, http.StatusOK, false},
+		{"DoNotFollowRedirects", task.Options{"follow_redirects": false}, http.StatusFound, false},
+		{"TooManyRedirects", task.Options{"max_redirects": 0}, 0, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			requestOptions := decodeHttpRequestOptions(t, tv.Options)
+			req, err := requestOptions.newRequest(context.Background(), testHttpServer.URL)
+			assert.NoError(t, err)
+
+			resp, err := requestOptions.client(http.DefaultClient).Do(req)
+			if tv.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, tv.ExpectedStatusCode, resp.StatusCode)
+		})
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper implemented by a function.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_HttpClient(t *testing.T) {
+	assert.Same(t, http.DefaultClient, httpClient(&task.Task{}))
+
+	var requestedUrl string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestedUrl = req.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("keyword")), Request: req}, nil
+	})}
+	alerter := &recordingAlerter{}
+	err := executeTask(WebScrape, &task.Task{
+		Options:    task.Options{"url": "http://hotalert.test", "keywords": []any{"keyword"}},
+		Timeout:    10 * time.Second,
+		Alerter:    alerter,
+		HttpClient: client,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://hotalert.test", requestedUrl)
+	assert.Equal(t, [][]string{{"keyword"}}, alerter.Alerts())
+}
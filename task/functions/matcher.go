@@ -0,0 +1,100 @@
+package functions
+
+import (
+	"bytes"
+	"io"
+)
+
+// matcherChunkSize is the number of bytes read at once by searchKeywords.
+const matcherChunkSize = 32 * 1024
+
+// streamMatcher searches for keywords in a stream of chunks. Keywords spanning chunk boundaries are found by
+// keeping the end of the previous chunk.
+type streamMatcher struct {
+	// keywords are the keywords to look for.
+	keywords [][]byte
+	// found marks the keywords found so far.
+	found []bool
+	// remaining is the number of keywords not found yet.
+	remaining int
+	// tail is the end of the previously scanned data, which is shorter than the longest keyword.
+	tail []byte
+	// overlap is the length of the longest keyword minus one.
+	overlap int
+}
+
+// newStreamMatcher returns a new streamMatcher instance for the given keywords.
+func newStreamMatcher(keywords []string) *streamMatcher {
+	matcher := &streamMatcher{
+		keywords:  make([][]byte, 0, len(keywords)),
+		found:     make([]bool, len(keywords)),
+		remaining: len(keywords),
+	}
+	for _, keyword := range keywords {
+		matcher.keywords = append(matcher.keywords, []byte(keyword))
+		if len(keyword)-1 > matcher.overlap {
+			matcher.overlap = len(keyword) - 1
+		}
+	}
+	return matcher
+}
+
+// Write scans the chunk for the keywords not found yet. It implements io.Writer and never returns an error.
+func (m *streamMatcher) Write(chunk []byte) (int, error) {
+	if m.Done() {
+		return len(chunk), nil
+	}
+	window := append(m.tail, chunk...)
+	for i, keyword := range m.keywords {
+		if !m.found[i] && bytes.Contains(window, keyword) {
+			m.found[i] = true
+			m.remaining -= 1
+		}
+	}
+	if len(window) > m.overlap {
+		window = window[len(window)-m.overlap:]
+	}
+	m.tail = append(m.tail[:0], window...)
+	return len(chunk), nil
+}
+
+// Done returns true when all the keywords were found.
+func (m *streamMatcher) Done() bool {
+	return m.remaining == 0
+}
+
+// Matched returns the keywords found so far, in the order they were given.
+func (m *streamMatcher) Matched() []string {
+	var matched = make([]string, 0, len(m.keywords))
+	for i, keyword := range m.keywords {
+		if m.found[i] {
+			matched = append(matched, string(keyword))
+		}
+	}
+	return matched
+}
+
+// searchKeywords reads the reader in chunks and returns the keywords found in it. Reading stops early when all
+// keywords were found or after maxSize bytes, in which case truncated is true.
+func searchKeywords(reader io.Reader, keywords []string, maxSize int64) (matched []string, truncated bool, err error) {
+	matcher := newStreamMatcher(keywords)
+	limitedReader := &io.LimitedReader{R: reader, N: maxSize}
+	var buffer = make([]byte, matcherChunkSize)
+	for !matcher.Done() {
+		n, err := limitedReader.Read(buffer)
+		_, _ = matcher.Write(buffer[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// The limit was reached if there is more data after maxSize bytes.
+	if limitedReader.N <= 0 && !matcher.Done() {
+		n, _ := reader.Read(buffer[:1])
+		truncated = n > 0
+	}
+	return matcher.Matched(), truncated, nil
+}
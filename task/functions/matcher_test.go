@@ -0,0 +1,57 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_StreamMatcher(t *testing.T) {
+	matcher := newStreamMatcher([]string{"Episode 10", "Episode 11", "Trailer"})
+	for _, chunk := range []string{"... Epi", "sode 1", "0 and Episo", "de 11 ..."} {
+		_, _ = matcher.Write([]byte(chunk))
+	}
+	assert.False(t, matcher.Done())
+	assert.Equal(t, []string{"Episode 10", "Episode 11"}, matcher.Matched())
+
+	_, _ = matcher.Write([]byte("Trailer"))
+	assert.True(t, matcher.Done())
+	assert.Equal(t, []string{"Episode 10", "Episode 11", "Trailer"}, matcher.Matched())
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += n
+	return n, err
+}
+
+func Test_SearchKeywords(t *testing.T) {
+	body := strings.Repeat("a", matcherChunkSize-3) + "keyword" + strings.Repeat("b", 10*matcherChunkSize) + "other"
+
+	// Keywords spanning chunk boundaries are found.
+	matched, truncated, err := searchKeywords(strings.NewReader(body), []string{"keyword", "other", "missing"}, int64(len(body)))
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, []string{"keyword", "other"}, matched)
+
+	// Reading stops early once all keywords are found.
+	reader := &countingReader{reader: strings.NewReader(body)}
+	matched, truncated, err = searchKeywords(reader, []string{"keyword"}, int64(len(body)))
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, []string{"keyword"}, matched)
+	assert.Equal(t, 2*matcherChunkSize, reader.count)
+
+	// Reading stops at maxSize.
+	matched, truncated, err = searchKeywords(strings.NewReader(body), []string{"keyword", "other"}, 2*matcherChunkSize)
+	assert.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Equal(t, []string{"keyword"}, matched)
+}
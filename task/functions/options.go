@@ -0,0 +1,55 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"hotalert/state"
+)
+
+// keywordOptions are the options which decide when an alert is posted given the keywords found in a document.
+type keywordOptions struct {
+	// Keywords are the keywords to look for.
+	Keywords []string `mapstructure:"keywords"`
+	// Match is the match mode, one of matchAny, matchAll or matchNone.
+	Match string `mapstructure:"match"`
+	// Condition is the optional boolean expression over keywords.
+	Condition string `mapstructure:"condition"`
+	// keywordMatch is built from the options by Validate.
+	keywordMatch *keywordMatch
+}
+
+// newKeywordOptions returns keywordOptions with the default values.
+func newKeywordOptions() keywordOptions {
+	return keywordOptions{Match: matchAny}
+}
+
+// Validate validates the options and builds the keywordMatch.
+func (o *keywordOptions) Validate() error {
+	var err error
+	o.keywordMatch, err = newKeywordMatch(o.Match, o.Keywords, o.Condition)
+	return err
+}
+
+// stateOptions are the options of task functions which keep state between runs.
+type stateOptions struct {
+	// StateDirectory is the directory where the state is kept.
+	StateDirectory string `mapstructure:"state_directory"`
+}
+
+// newStateOptions returns stateOptions with the default values.
+func newStateOptions() stateOptions {
+	return stateOptions{StateDirectory: state.DefaultDirectory()}
+}
+
+// store returns the state store kept in the state directory.
+func (o *stateOptions) store() state.Store {
+	return state.NewFileStore(o.StateDirectory)
+}
+
+// requireOption returns an error if the value of the required option is empty.
+func requireOption(name string, value string) error {
+	if value == "" {
+		return errors.New(fmt.Sprintf("invalid task parameter %s, %s is required", name, name))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/state"
+	"hotalert/task"
+	"testing"
+)
+
+func Test_KeywordOptions(t *testing.T) {
+	options := newKeywordOptions()
+	assert.NoError(t, task.DecodeOptions(task.Options{"keywords": []any{"a", "b"}}, &options))
+	assert.NoError(t, options.Validate())
+	assert.Equal(t, []string{"a", "b"}, options.keywordMatch.SearchedKeywords())
+	assert.Equal(t, matchAny, options.keywordMatch.Mode)
+
+	options = newKeywordOptions()
+	assert.NoError(t, task.DecodeOptions(task.Options{"condition": `"a" and not "b"`}, &options))
+	assert.NoError(t, options.Validate())
+	assert.Equal(t, []string{"a", "b"}, options.keywordMatch.SearchedKeywords())
+
+	options = newKeywordOptions()
+	assert.Error(t, task.DecodeOptions(task.Options{"keywords": "a"}, &options))
+	options = newKeywordOptions()
+	assert.Error(t, options.Validate())
+	options = keywordOptions{Keywords: []string{"a"}, Match: "some"}
+	assert.Error(t, options.Validate())
+}
+
+func Test_StateOptions(t *testing.T) {
+	options := newStateOptions()
+	assert.Equal(t, state.DefaultDirectory(), options.StateDirectory)
+
+	directory := t.TempDir()
+	assert.NoError(t, task.DecodeOptions(task.Options{"state_directory": directory}, &options))
+	assert.Equal(t, state.NewFileStore(directory), options.store())
+}
+
+func Test_RequireOption(t *testing.T) {
+	assert.NoError(t, requireOption("url", "http://example.com"))
+	assert.EqualError(t, requireOption("url", ""), "invalid task parameter url, url is required")
+}
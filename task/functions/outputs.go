@@ -0,0 +1,12 @@
+package functions
+
+import (
+	"context"
+	"hotalert/task"
+)
+
+// setOutput sets an output of the task, which the tasks depending on it can use as ${tasks.<id>.<name>}.
+// It is a shorthand for task.SetOutput in functions whose task parameter shadows the task package.
+func setOutput(ctx context.Context, name string, value string) {
+	task.SetOutput(ctx, name, value)
+}
@@ -0,0 +1,97 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// prerenderUrl returns the url which fetches the target url through the prerender service.
+// The target url replaces the {url} placeholder, query escaped, or is appended to the service url.
+func prerenderUrl(serviceUrl string, targetUrl string) string {
+	if strings.Contains(serviceUrl, "{url}") {
+		return strings.Replace(serviceUrl, "{url}", url.QueryEscape(targetUrl), -1)
+	}
+	return serviceUrl + targetUrl
+}
+
+// discoverXhrUrl fetches the page and returns the endpoint url found by the first group of the pattern.
+// Relative endpoint urls are resolved against the page url.
+func discoverXhrUrl(ctx context.Context, client *http.Client, requestOptions *httpRequestOptions, pageUrl string, pattern *regexp.Regexp, maxBodySize int64) (string, error) {
+	page, resp, err := fetchPage(ctx, client, requestOptions, pageUrl, maxBodySize)
+	if err != nil {
+		return "", err
+	}
+
+	match := pattern.FindSubmatch(page)
+	if len(match) < 2 {
+		return "", errors.New(fmt.Sprintf("no endpoint matching %s found on %s", pattern, pageUrl))
+	}
+	endpointUrl, err := url.Parse(strings.Replace(string(match[1]), `\/`, "/", -1))
+	if err != nil {
+		return "", err
+	}
+	return resp.Request.URL.ResolveReference(endpointUrl).String(), nil
+}
+
+// isJsonResponse returns true if the response has a JSON content type.
+func isJsonResponse(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonText decodes the JSON document and returns a reader with its values, one per line, so that keywords are matched
+// against the unescaped text. If path is not empty, only the values under the dot separated path are returned.
+func jsonText(document io.Reader, path string) (io.Reader, error) {
+	var value any
+	if err := json.NewDecoder(document).Decode(&value); err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to decode json: %s", err))
+	}
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("json path %s not found", path))
+			}
+			value, ok = object[key]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("json path %s not found", path))
+			}
+		}
+	}
+
+	var text strings.Builder
+	writeJsonValues(&text, value)
+	return strings.NewReader(text.String()), nil
+}
+
+// writeJsonValues writes the scalar values of the decoded JSON value, one per line. Object keys are written in order.
+func writeJsonValues(text *strings.Builder, value any) {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		var keys = make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			writeJsonValues(text, typedValue[key])
+		}
+	case []any:
+		for _, item := range typedValue {
+			writeJsonValues(text, item)
+		}
+	case nil:
+	default:
+		text.WriteString(fmt.Sprint(typedValue))
+		text.WriteByte('\n')
+	}
+}
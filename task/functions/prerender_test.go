@@ -0,0 +1,55 @@
+package functions
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_PrerenderUrl(t *testing.T) {
+	assert.Equal(t, "http://localhost:3000/render?url=https%3A%2F%2Fshop.example%2Fp%3Fid%3D1",
+		prerenderUrl("http://localhost:3000/render?url={url}", "https://shop.example/p?id=1"))
+	assert.Equal(t, "http://localhost:3000/https://shop.example/p?id=1",
+		prerenderUrl("http://localhost:3000/", "https://shop.example/p?id=1"))
+}
+
+func Test_DiscoverXhrUrl(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`<script>window.config = {"api": "\/api\/products?page=1"};</script>`))
+	}))
+	defer testHttpServer.Close()
+
+	requestOptions := decodeHttpRequestOptions(t, nil)
+	xhrUrl, err := discoverXhrUrl(context.Background(), http.DefaultClient, requestOptions, testHttpServer.URL+"/shop/",
+		regexp.MustCompile(`"api": "([^"]+)"`), defaultMaxBodySize)
+	assert.NoError(t, err)
+	assert.Equal(t, testHttpServer.URL+"/api/products?page=1", xhrUrl)
+
+	_, err = discoverXhrUrl(context.Background(), http.DefaultClient, requestOptions, testHttpServer.URL,
+		regexp.MustCompile(`"graphql": "([^"]+)"`), defaultMaxBodySize)
+	assert.Error(t, err)
+}
+
+func Test_JsonText(t *testing.T) {
+	document := `{"data": {"items": [{"name": "Placă video", "stock": 3, "preorder": false}]}, "meta": {"page": 1}}`
+
+	reader, err := jsonText(strings.NewReader(document), "")
+	assert.NoError(t, err)
+	text, _ := io.ReadAll(reader)
+	assert.Equal(t, "Placă video\nfalse\n3\n1\n", string(text))
+
+	reader, err = jsonText(strings.NewReader(document), "data.items")
+	assert.NoError(t, err)
+	text, _ = io.ReadAll(reader)
+	assert.Equal(t, "Placă video\nfalse\n3\n", string(text))
+
+	_, err = jsonText(strings.NewReader(document), "data.missing")
+	assert.Error(t, err)
+	_, err = jsonText(strings.NewReader("<html>"), "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package functions
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsAgent is the product token matched against the user-agent lines of robots.txt files.
+const robotsAgent = "hotalert"
+
+// robotsMaxSize is the maximum number of bytes read from a robots.txt file.
+const robotsMaxSize = 512 * 1024
+
+// robotsCacheDuration is the duration for which a robots.txt file is cached.
+const robotsCacheDuration = 24 * time.Hour
+
+// robotsRule is an allow or disallow rule of a robots.txt file.
+type robotsRule struct {
+	allow   bool
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// robotsRules are the rules of a robots.txt file which apply to hotalert.
+type robotsRules struct {
+	rules []robotsRule
+}
+
+// robotsCacheEntry is a cached robots.txt file.
+type robotsCacheEntry struct {
+	rules   *robotsRules
+	expires time.Time
+}
+
+// robotsCache holds the robots.txt files by scheme and host.
+var robotsCache = struct {
+	sync.Mutex
+	entries map[string]robotsCacheEntry
+}{entries: make(map[string]robotsCacheEntry)}
+
+// checkRobots returns an error if robots.txt checks are enabled and the robots.txt file of the host disallows the
+// url. The robots.txt file is fetched with the client and cached. A missing robots.txt file allows all urls, while a
+// robots.txt file which cannot be fetched because of a server or network error disallows all urls.
+func (o *httpRequestOptions) checkRobots(ctx context.Context, client *http.Client, targetUrl string) error {
+	if !o.RobotsTxt {
+		return nil
+	}
+	parsedUrl, err := url.Parse(targetUrl)
+	if err != nil {
+		return err
+	}
+	rules, err := fetchRobots(ctx, client, parsedUrl)
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to fetch robots.txt: %s", err))
+	}
+	if !rules.Allowed(parsedUrl.RequestURI()) {
+		return errors.New(fmt.Sprintf("%s is disallowed by robots.txt", targetUrl))
+	}
+	return nil
+}
+
+// fetchRobots returns the rules of the robots.txt file of the url host, from the cache if possible.
+func fetchRobots(ctx context.Context, client *http.Client, pageUrl *url.URL) (*robotsRules, error) {
+	key := pageUrl.Scheme + "://" + pageUrl.Host
+	robotsCache.Lock()
+	entry, ok := robotsCache.entries[key]
+	robotsCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.rules, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rules = &robotsRules{}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		rules = parseRobots(io.LimitReader(resp.Body, robotsMaxSize))
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+		// No robots.txt file, all urls are allowed.
+	default:
+		return nil, statusCodeError("failed to query robots.txt", resp.StatusCode)
+	}
+
+	robotsCache.Lock()
+	robotsCache.entries[key] = robotsCacheEntry{rules: rules, expires: time.Now().Add(robotsCacheDuration)}
+	robotsCache.Unlock()
+	return rules, nil
+}
+
+// parseRobots parses a robots.txt file and returns the rules of the groups whose user-agent is hotalert, or of the
+// groups whose user-agent is * if there are none.
+func parseRobots(reader io.Reader) *robotsRules {
+	var agentRules, defaultRules []robotsRule
+	var groupAgents []string
+	var inRules bool
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			// User-agent lines which follow rules start a new group.
+			if inRules {
+				groupAgents, inRules = nil, false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value, regex: robotsPattern(value)}
+			for _, agent := range groupAgents {
+				switch {
+				case agent == robotsAgent:
+					agentRules = append(agentRules, rule)
+				case agent == "*":
+					defaultRules = append(defaultRules, rule)
+				}
+			}
+		}
+	}
+	if agentRules != nil {
+		return &robotsRules{rules: agentRules}
+	}
+	return &robotsRules{rules: defaultRules}
+}
+
+// robotsPattern compiles the path pattern of a rule, in which * matches any characters and a trailing $ matches the
+// end of the path.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expression := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1)
+	if anchored {
+		expression += "$"
+	}
+	return regexp.MustCompile(expression)
+}
+
+// Allowed returns true if the path is allowed. The longest matching rule decides, allow rules win ties.
+func (r *robotsRules) Allowed(path string) bool {
+	var allowed, longest = true, -1
+	for _, rule := range r.rules {
+		if !rule.regex.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,159 @@
+package functions
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRobotsTxt = `
+# Rules for all crawlers.
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: otherbot
+Disallow: /
+`
+
+var testRobotsTxtForHotalert = `
+User-agent: *
+Disallow: /
+
+User-agent: Hotalert
+User-agent: otherbot
+Disallow: /search
+`
+
+func Test_RobotsRules_Allowed(t *testing.T) {
+	var tests = []struct {
+		TestName        string
+		RobotsTxt       string
+		Path            string
+		ExpectedAllowed bool
+	}{
+		{"NotMatched", testRobotsTxt, "/products", true},
+		{"Disallowed", testRobotsTxt, "/private/page", false},
+		{"LongerAllowWins", testRobotsTxt, "/private/public/page", true},
+		{"WildcardAnchored", testRobotsTxt, "/files/manual.pdf", false},
+		{"WildcardNotAtEnd", testRobotsTxt, "/files/manual.pdf?page=2", true},
+		{"AgentGroup", testRobotsTxtForHotalert, "/products", true},
+		{"AgentGroupDisallowed", testRobotsTxtForHotalert, "/search?q=phone", false},
+		{"Empty", "", "/private", true},
+		{"EmptyDisallow", "User-agent: *\nDisallow:\n", "/private", true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(tv.RobotsTxt))
+			assert.Equal(t, tv.ExpectedAllowed, rules.Allowed(tv.Path))
+		})
+	}
+}
+
+func Test_CheckRobots(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		RobotsTxt     bool
+		StatusCode    int
+		Path          string
+		ExpectedError bool
+	}{
+		{"Disabled", false, http.StatusOK, "/private", false},
+		{"Allowed", true, http.StatusOK, "/products", false},
+		{"Disallowed", true, http.StatusOK, "/private", true},
+		{"NoRobotsTxt", true, http.StatusNotFound, "/private", false},
+		{"ServerError", true, http.StatusServiceUnavailable, "/products", true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(tv.StatusCode)
+				_, _ = writer.Write([]byte(testRobotsTxt))
+			}))
+			defer testHttpServer.Close()
+
+			requestOptions := newHttpRequestOptions()
+			requestOptions.RobotsTxt = tv.RobotsTxt
+			err := requestOptions.checkRobots(context.Background(), http.DefaultClient, testHttpServer.URL+tv.Path)
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestScrapeWebTask_RobotsTxt(t *testing.T) {
+	var robotsRequests, pageRequests int
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/robots.txt" {
+			robotsRequests += 1
+			_, _ = writer.Write([]byte(testRobotsTxt))
+			return
+		}
+		pageRequests += 1
+		_, _ = writer.Write([]byte("keyword"))
+	}))
+	defer testHttpServer.Close()
+
+	for _, path := range []string{"/products", "/private"} {
+		_ = executeTask(WebScrape, &task.Task{
+			Options: task.Options{
+				"url":        testHttpServer.URL + path,
+				"keywords":   []any{"keyword"},
+				"robots_txt": true,
+			},
+			Timeout: 10 * time.Second,
+			Alerter: alert.NewDummyAlerter(),
+		})
+	}
+
+	// The robots.txt file is cached and the disallowed page is not requested.
+	assert.Equal(t, 1, robotsRequests)
+	assert.Equal(t, 1, pageRequests)
+}
+
+func TestScrapeWebTask_RobotsTxtPrerender(t *testing.T) {
+	var renderRequests int
+	siteServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/robots.txt" {
+			_, _ = writer.Write([]byte(testRobotsTxt))
+			return
+		}
+		_, _ = writer.Write([]byte("keyword"))
+	}))
+	defer siteServer.Close()
+	// The prerender service allows all urls.
+	prerenderServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/render" {
+			renderRequests += 1
+			_, _ = writer.Write([]byte("keyword"))
+		}
+	}))
+	defer prerenderServer.Close()
+
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":           siteServer.URL + "/private",
+			"keywords":      []any{"keyword"},
+			"robots_txt":    true,
+			"prerender_url": prerenderServer.URL + "/render?url={url}",
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alert.NewDummyAlerter(),
+	})
+
+	// The robots.txt file of the site disallows the page, so it is not prerendered.
+	assert.EqualError(t, err, siteServer.URL+"/private is disallowed by robots.txt")
+	assert.Equal(t, 0, renderRequests)
+}
@@ -0,0 +1,202 @@
+package functions
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html/charset"
+	"hotalert/hostlimit"
+	"hotalert/logging"
+	"hotalert/task"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// feedItem represents an item of an RSS feed or an entry of an Atom feed.
+type feedItem struct {
+	Guid        string
+	Title       string
+	Link        string
+	Description string
+}
+
+// rssDocument is the XML structure of an RSS 2.0 feed.
+type rssDocument struct {
+	Items []struct {
+		Guid        string `xml:"guid"`
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel>item"`
+}
+
+// atomDocument is the XML structure of an Atom feed.
+type atomDocument struct {
+	Entries []struct {
+		Id    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary string `xml:"summary"`
+		Content string `xml:"content"`
+	} `xml:"entry"`
+}
+
+// rssWatchOptions are the options of RssWatch.
+type rssWatchOptions struct {
+	// Url is the url of the feed.
+	Url string `mapstructure:"url"`
+	// Keywords are the keywords searched in the new items. All new items match when it is empty.
+	Keywords []string `mapstructure:"keywords"`
+	// MaxBodySize is the maximum number of response bytes read.
+	MaxBodySize  int `mapstructure:"max_body_size"`
+	stateOptions `mapstructure:",squash"`
+}
+
+// Validate validates the options.
+func (o *rssWatchOptions) Validate() error {
+	if err := requireOption("url", o.Url); err != nil {
+		return err
+	}
+	if o.MaxBodySize <= 0 {
+		return errors.New(fmt.Sprintf("invalid task parameter max_body_size %d, must be positive", o.MaxBodySize))
+	}
+	return nil
+}
+
+// RssWatch fetches an RSS or Atom feed and alerts on new items matching the given keywords.
+// The GUIDs of the items that were already seen are kept in the task state, by url and keywords.
+// Feeds are read up to max_body_size bytes and transcoded from the charset declared in their XML declaration.
+var RssWatch = task.NewFunction(
+	"Alerts on new RSS or Atom feed items which contain keywords.",
+	func() *rssWatchOptions {
+		return &rssWatchOptions{MaxBodySize: defaultMaxBodySize, stateOptions: newStateOptions()}
+	},
+	rssWatch,
+)
+
+// rssWatch executes the task given its decoded options.
+func rssWatch(ctx context.Context, task *task.Task, options *rssWatchOptions) error {
+	feedUrl, store := options.Url, options.store()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedUrl, nil)
+	if err != nil {
+		logging.SugaredLogger.Errorf("failed to build http request: %s", err)
+		return err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := hostlimit.Client(httpClient(task)).Do(req)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to fetch feed: %s", err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logging.SugaredLogger.Errorf("Failed to fetch feed, status code %d", resp.StatusCode)
+		return statusCodeError("Failed to fetch feed", resp.StatusCode)
+	}
+
+	var document struct {
+		rssDocument
+		atomDocument
+	}
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, int64(options.MaxBodySize)))
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&document); err != nil {
+		logging.SugaredLogger.Errorf("Failed to parse feed: %s", err)
+		return err
+	}
+	items := feedItems(&document.rssDocument, &document.atomDocument)
+
+	// Load the GUIDs seen on the previous runs. Tasks which watch the same feed for different keywords keep their own
+	// seen items.
+	stateKey := fmt.Sprintf("rss_watch %s %q", feedUrl, options.Keywords)
+	var seenGuids []string
+	if _, err := store.Load(stateKey, &seenGuids); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	var seen = make(map[string]bool, len(seenGuids))
+	for _, guid := range seenGuids {
+		seen[guid] = true
+	}
+
+	// Search for new items matching the keywords.
+	var matchedItems = make([]string, 0, 10)
+	var currentGuids = make([]string, 0, len(items))
+	for _, item := range items {
+		currentGuids = append(currentGuids, item.Guid)
+		if seen[item.Guid] || !itemMatches(item, options.Keywords) {
+			continue
+		}
+		matchedItems = append(matchedItems, fmt.Sprintf("%s - %s", item.Title, item.Link))
+	}
+
+	// If we have matched items post an alert.
+	if len(matchedItems) > 0 {
+		task.Alerter.PostAlert(ctx, matchedItems)
+	}
+
+	// Only the items still present in the feed are remembered, which keeps the state small.
+	if err := store.Save(stateKey, currentGuids); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// feedItems converts the parsed RSS items or Atom entries into feed items.
+func feedItems(rss *rssDocument, atom *atomDocument) []feedItem {
+	var items = make([]feedItem, 0, len(rss.Items)+len(atom.Entries))
+	for _, rssItem := range rss.Items {
+		items = append(items, feedItem{
+			Guid:        rssItem.Guid,
+			Title:       strings.TrimSpace(rssItem.Title),
+			Link:        strings.TrimSpace(rssItem.Link),
+			Description: rssItem.Description,
+		})
+	}
+	for _, entry := range atom.Entries {
+		item := feedItem{
+			Guid:        entry.Id,
+			Title:       strings.TrimSpace(entry.Title),
+			Description: entry.Summary + " " + entry.Content,
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				item.Link = link.Href
+				break
+			}
+		}
+		items = append(items, item)
+	}
+
+	// Fallback to the link or title when the feed has no GUIDs.
+	for i := range items {
+		if items[i].Guid == "" {
+			items[i].Guid = items[i].Link
+		}
+		if items[i].Guid == "" {
+			items[i].Guid = items[i].Title
+		}
+	}
+	return items
+}
+
+// itemMatches returns true if the item title or description contains any of the keywords.
+// All items match when no keywords are given.
+func itemMatches(item feedItem, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	for _, keyword := range keywords {
+		if strings.Contains(item.Title, keyword) || strings.Contains(item.Description, keyword) {
+			return true
+		}
+	}
+	return false
+}
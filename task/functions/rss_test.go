@@ -0,0 +1,230 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testRssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Jobs</title>
+    <item>
+      <title>Software Engineer, Backend</title>
+      <link>https://jobs.example/1</link>
+      <guid>job-1</guid>
+      <description>Go and Kubernetes</description>
+    </item>
+    <item>
+      <title>Accountant</title>
+      <link>https://jobs.example/2</link>
+      <guid>job-2</guid>
+    </item>
+  </channel>
+</rss>`
+
+var testRssFeedUpdated = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Jobs</title>
+    <item>
+      <title>Software Engineer, Backend</title>
+      <link>https://jobs.example/1</link>
+      <guid>job-1</guid>
+    </item>
+    <item>
+      <title>Software Architect</title>
+      <link>https://jobs.example/3</link>
+      <guid>job-3</guid>
+    </item>
+  </channel>
+</rss>`
+
+var testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Episodes</title>
+  <entry>
+    <title>Episode 10</title>
+    <link rel="alternate" href="https://shows.example/10"/>
+    <id>urn:episode:10</id>
+    <summary>The tenth episode</summary>
+  </entry>
+  <entry>
+    <title>Trailer</title>
+    <link href="https://shows.example/trailer"/>
+    <id>urn:trailer</id>
+  </entry>
+</feed>`
+
+func TestRssWatchTask(t *testing.T) {
+	var feed = testRssFeed
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(feed))
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	currentTask := &task.Task{
+		Options: task.Options{
+			"url":             testHttpServer.URL,
+			"keywords":        []any{"Software"},
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	}
+
+	// First run alerts on the matching items.
+	assert.NoError(t, executeTask(RssWatch, currentTask))
+	assert.Equal(t, [][]string{{"Software Engineer, Backend - https://jobs.example/1"}}, alerter.Alerts())
+
+	// Second run does not alert on items already seen.
+	assert.NoError(t, executeTask(RssWatch, currentTask))
+	assert.Len(t, alerter.Alerts(), 1)
+
+	// New matching items are alerted.
+	feed = testRssFeedUpdated
+	assert.NoError(t, executeTask(RssWatch, currentTask))
+	assert.Equal(t, []string{"Software Architect - https://jobs.example/3"}, alerter.Alerts()[1])
+}
+
+func TestRssWatchTask_SameFeed(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(testRssFeed))
+	}))
+	defer testHttpServer.Close()
+	stateDirectory := t.TempDir()
+
+	newTask := func(keyword string, alerter *recordingAlerter) *task.Task {
+		return &task.Task{
+			Options: task.Options{
+				"url":             testHttpServer.URL,
+				"keywords":        []any{keyword},
+				"state_directory": stateDirectory,
+			},
+			Timeout: 10 * time.Second,
+			Alerter: alerter,
+		}
+	}
+
+	// The items seen by a task which did not match its keywords are still new for the other task.
+	softwareAlerter, accountantAlerter := &recordingAlerter{}, &recordingAlerter{}
+	assert.NoError(t, executeTask(RssWatch, newTask("Software", softwareAlerter)))
+	assert.NoError(t, executeTask(RssWatch, newTask("Accountant", accountantAlerter)))
+	assert.Equal(t, [][]string{{"Software Engineer, Backend - https://jobs.example/1"}}, softwareAlerter.Alerts())
+	assert.Equal(t, [][]string{{"Accountant - https://jobs.example/2"}}, accountantAlerter.Alerts())
+}
+
+func TestRssWatchTask_Charset(t *testing.T) {
+	var userAgent string
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		userAgent = request.Header.Get("User-Agent")
+		// "Vânzător" encoded as ISO-8859-2.
+		_, _ = writer.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-2"?><rss><channel><item>` +
+			"<guid>1</guid><title>V\xe2nz\xe3tor</title><link>https://jobs.example/1</link></item></channel></rss>"))
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	err := executeTask(RssWatch, &task.Task{
+		Options: task.Options{
+			"url":             testHttpServer.URL,
+			"keywords":        []any{"Vânzător"},
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Vânzător - https://jobs.example/1"}}, alerter.Alerts())
+	assert.Equal(t, defaultUserAgent, userAgent)
+}
+
+func TestRssWatchTask_Atom(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(testAtomFeed))
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	err := executeTask(RssWatch, &task.Task{
+		Options: task.Options{
+			"url":             testHttpServer.URL,
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{
+		"Episode 10 - https://shows.example/10",
+		"Trailer - https://shows.example/trailer",
+	}}, alerter.Alerts())
+}
+
+func TestRssWatchTask_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName   string
+		Options    task.Options
+		ServerFunc http.HandlerFunc
+	}{
+		{
+			"MissingUrl",
+			task.Options{},
+			func(writer http.ResponseWriter, request *http.Request) {},
+		},
+		{
+			"InvalidKeywords",
+			task.Options{"keywords": "Software"},
+			func(writer http.ResponseWriter, request *http.Request) {},
+		},
+		{
+			"BadResponse",
+			task.Options{},
+			func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(500)
+			},
+		},
+		{
+			"InvalidMaxBodySize",
+			task.Options{"max_body_size": 0},
+			func(writer http.ResponseWriter, request *http.Request) {},
+		},
+		{
+			"FeedTooLarge",
+			task.Options{"max_body_size": 100},
+			func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte(testRssFeed))
+			},
+		},
+		{
+			"InvalidFeed",
+			task.Options{},
+			func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte("<rss><channel>"))
+			},
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			testHttpServer := httptest.NewServer(tv.ServerFunc)
+			defer testHttpServer.Close()
+			if tv.TestName != "MissingUrl" {
+				tv.Options["url"] = testHttpServer.URL
+			}
+			tv.Options["state_directory"] = t.TempDir()
+
+			err := executeTask(RssWatch, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: &recordingAlerter{},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -6,75 +6,163 @@ import (
 	"fmt"
 	"hotalert/logging"
 	"hotalert/task"
-	"io/ioutil"
-	"net/http"
+	"io"
+	"regexp"
 	"strings"
 )
 
-// WebScrapeTask scraps the web page given the task.
-func WebScrapeTask(task *task.Task) error {
-	// Parse options
-	targetUrl, ok := task.Options["url"].(string)
-	if !ok {
-		logging.SugaredLogger.Errorf("Invalid task parameter url %v", targetUrl)
-		return errors.New(fmt.Sprintf("Invalid task parameter url %v", targetUrl))
+// defaultMaxBodySize is the default maximum number of response bytes scanned by WebScrapeTask.
+const defaultMaxBodySize = 10 * 1024 * 1024
+
+// webScrapeOptions are the options of WebScrape.
+type webScrapeOptions struct {
+	// Url is the url of the page.
+	Url                string `mapstructure:"url"`
+	keywordOptions     `mapstructure:",squash"`
+	httpRequestOptions `mapstructure:",squash"`
+	// MaxBodySize is the maximum number of response bytes scanned for keywords.
+	MaxBodySize int `mapstructure:"max_body_size"`
+	// PrerenderUrl is the optional url of the prerender service which renders the page.
+	PrerenderUrl string `mapstructure:"prerender_url"`
+	// XhrUrl is the optional url of the endpoint which holds the content of the page.
+	XhrUrl string `mapstructure:"xhr_url"`
+	// XhrDiscover is the optional regex which finds the url of the endpoint in the page.
+	XhrDiscover string `mapstructure:"xhr_discover"`
+	// JsonPath is the optional path which limits the search to a part of a JSON response.
+	JsonPath string `mapstructure:"json_path"`
+	// xhrPattern is the compiled XhrDiscover.
+	xhrPattern *regexp.Regexp
+}
+
+// Validate validates the options.
+func (o *webScrapeOptions) Validate() error {
+	if err := requireOption("url", o.Url); err != nil {
+		return err
+	}
+	if err := o.keywordOptions.Validate(); err != nil {
+		return err
+	}
+	if err := o.httpRequestOptions.Validate(); err != nil {
+		return err
+	}
+	if o.MaxBodySize <= 0 {
+		return errors.New(fmt.Sprintf("invalid task parameter max_body_size %d, must be positive", o.MaxBodySize))
+	}
+	if o.XhrDiscover != "" {
+		var err error
+		o.xhrPattern, err = regexp.Compile(o.XhrDiscover)
+		if err != nil || o.xhrPattern.NumSubexp() < 1 {
+			return errors.New(fmt.Sprintf("invalid task parameter xhr_discover %s, a regex with a group is required", o.XhrDiscover))
+		}
+	}
+	return nil
+}
+
+// WebScrape scraps the web page given the task.
+// The request is built from the http request options of the task, see httpRequestOptions.
+// The response is decoded to UTF-8 text, see decodeResponseBody, and scanned in chunks up to max_body_size bytes.
+// Reading stops once all keywords were found. The match and condition options decide when an alert is posted.
+// Pages rendered by JavaScript are supported by fetching the declared or discovered XHR endpoint, whose JSON values
+// are matched, or by fetching the page through a prerender service. The robots.txt file of the page url applies in
+// all cases, while the host limits count the requests by the host they are sent to, which is the prerender service
+// host for prerendered pages.
+var WebScrape = task.NewFunction(
+	"Scrapes a web page and alerts when keywords are found.",
+	func() *webScrapeOptions {
+		return &webScrapeOptions{
+			keywordOptions:     newKeywordOptions(),
+			httpRequestOptions: newHttpRequestOptions(),
+			MaxBodySize:        defaultMaxBodySize,
+		}
+	},
+	webScrape,
+)
+
+// webScrape executes the task given its decoded options.
+func webScrape(ctx context.Context, task *task.Task, options *webScrapeOptions) error {
+	targetUrl := options.Url
+	maxBodySize := int64(options.MaxBodySize)
+	requestOptions := &options.httpRequestOptions
+
+	client := requestOptions.client(httpClient(task))
+
+	// The robots.txt file of the page applies, also when its content is fetched from elsewhere.
+	if err := requestOptions.checkRobots(ctx, client, options.Url); err != nil {
+		logging.SugaredLogger.Errorf("Failed to scrap page: %s", err)
+		return err
 	}
-	keywords, ok := task.Options["keywords"]
-	if !ok {
-		logging.SugaredLogger.Errorf("Invalid task parameter keywords %v", keywords)
-		return errors.New(fmt.Sprintf("Invalid parameter keywords %v", keywords))
+
+	// Figure out the url which holds the content: the declared or discovered endpoint, or the prerendered page.
+	var err error
+	xhr := options.XhrUrl != "" || options.xhrPattern != nil
+	switch {
+	case options.XhrUrl != "":
+		targetUrl = options.XhrUrl
+	case options.xhrPattern != nil:
+		targetUrl, err = discoverXhrUrl(ctx, client, requestOptions, targetUrl, options.xhrPattern, maxBodySize)
+		if err != nil {
+			logging.SugaredLogger.Errorf("Failed to discover endpoint: %s", err)
+			return err
+		}
+	case options.PrerenderUrl != "":
+		targetUrl = prerenderUrl(options.PrerenderUrl, targetUrl)
 	}
 
-	// Create a context with timeout specific to task.
-	ctx, cancel := context.WithTimeout(context.Background(), task.Timeout)
-	defer cancel()
+	// The endpoint is part of a site as well, which may be another one.
+	if xhr {
+		if err := requestOptions.checkRobots(ctx, client, targetUrl); err != nil {
+			logging.SugaredLogger.Errorf("Failed to scrap page: %s", err)
+			return err
+		}
+	}
 
 	// Create a request with timeout.
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetUrl, nil)
+	req, err := requestOptions.newRequest(ctx, targetUrl)
 	if err != nil {
 		logging.SugaredLogger.Errorf("failed to build http request: %s", err)
 		return err
 	}
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		logging.SugaredLogger.Errorf("Failed to scrap page: %s", err)
 		return err
 	} else {
-		if resp.StatusCode == 200 {
-			pageBody, err := ioutil.ReadAll(resp.Body)
+		defer resp.Body.Close()
+		if requestOptions.acceptsStatusCode(resp.StatusCode) {
+			pageBody, err := decodeResponseBody(resp, maxBodySize)
 			if err != nil {
-				logging.SugaredLogger.Errorf("Failed to read response from page. %s", err)
+				logging.SugaredLogger.Errorf("Failed to decode response from page. %s", err)
 				return err
 			}
-			pageBodyStr := string(pageBody)
+			// Only the values of endpoint responses are searched, plain JSON pages are searched as they are.
+			if (xhr && isJsonResponse(resp)) || options.JsonPath != "" {
+				pageBody, err = jsonText(io.LimitReader(pageBody, maxBodySize), options.JsonPath)
+				if err != nil {
+					logging.SugaredLogger.Errorf("Failed to decode response from page. %s", err)
+					return err
+				}
+			}
 
 			// Search for matched keywords and save them.
-			var matchedKeywords = make([]string, 0, 10)
-			keywordsLst, ok := keywords.([]any)
-			if !ok {
-				logging.SugaredLogger.Errorf("Invalid task parameter keywords %v", keywords)
-				return errors.New(fmt.Sprintf("Invalid parameter keywords %v", keywords))
+			matchedKeywords, truncated, err := searchKeywords(pageBody, options.keywordMatch.SearchedKeywords(), maxBodySize)
+			if err != nil {
+				logging.SugaredLogger.Errorf("Failed to read response from page. %s", err)
+				return err
 			}
-			for _, value := range keywordsLst {
-				valueStr, ok := value.(string)
-				if !ok {
-					logging.SugaredLogger.Errorf("Invalid value in task keywords, not a string %v", valueStr)
-					return errors.New(fmt.Sprintf("Invalid value in task keywords, not a string %v", valueStr))
-				}
-				if strings.Contains(pageBodyStr, valueStr) {
-					matchedKeywords = append(matchedKeywords, valueStr)
-				}
+			setOutput(ctx, "matched", strings.Join(matchedKeywords, ","))
+			if truncated {
+				logging.SugaredLogger.Warnf("Response from %s is larger than %d bytes, the rest was not scanned and alerts on missing keywords are skipped", targetUrl, maxBodySize)
 			}
 
-			// If we have matched keywords post an alert.
-			if len(matchedKeywords) > 0 {
-				task.Alerter.PostAlert(context.Background(), matchedKeywords)
+			// If the match condition holds post an alert.
+			if alertContext := options.keywordMatch.Evaluate(matchedKeywords, truncated); alertContext != nil {
+				task.Alerter.PostAlert(ctx, alertContext)
 			}
 		} else {
 			logging.SugaredLogger.Errorf("Failed to query website, status code %d", resp.StatusCode)
-			return errors.New(fmt.Sprintf("Failed to query website, status code %d", resp.StatusCode))
+			return statusCodeError("Failed to query website", resp.StatusCode)
 		}
 	}
 	return nil
@@ -1,7 +1,10 @@
 package functions
 
 import (
+	"errors"
+	"github.com/andybalholm/brotli"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
 	"hotalert/alert"
 	"hotalert/task"
 	"net/http"
@@ -99,6 +102,95 @@ func TestScrapeWebTask(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"TestAcceptedStatusCode",
+			task.Task{
+				Options: task.Options{
+					"keywords":     []any{"keyword"},
+					"status_codes": []any{200, 404},
+				},
+				Timeout:  10 * time.Second,
+				Alerter:  alert.NewDummyAlerter(),
+				Callback: nil,
+			},
+			true,
+			func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(404)
+				_, _ = writer.Write([]byte("keyword"))
+			},
+			false,
+		},
+		{
+			"TestRequestOptions",
+			task.Task{
+				Options: task.Options{
+					"keywords": []any{"keyword"},
+					"method":   "POST",
+					"headers":  map[string]any{"User-Agent": "hotalert-test"},
+				},
+				Timeout:  10 * time.Second,
+				Alerter:  alert.NewDummyAlerter(),
+				Callback: nil,
+			},
+			true,
+			func(writer http.ResponseWriter, request *http.Request) {
+				if request.Method != http.MethodPost || request.UserAgent() != "hotalert-test" {
+					writer.WriteHeader(400)
+				}
+			},
+			false,
+		},
+		{
+			"TestInvalidRequestOptions",
+			task.Task{
+				Options: task.Options{
+					"keywords": []any{"keyword"},
+					"headers":  "User-Agent",
+				},
+				Timeout:  10 * time.Second,
+				Alerter:  alert.NewDummyAlerter(),
+				Callback: nil,
+			},
+			true,
+			func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte("ok"))
+			},
+			true,
+		},
+		{
+			"TestMaxBodySize",
+			task.Task{
+				Options: task.Options{
+					"keywords":      []any{"keyword"},
+					"max_body_size": 4,
+				},
+				Timeout:  10 * time.Second,
+				Alerter:  alert.NewDummyAlerter(),
+				Callback: nil,
+			},
+			true,
+			func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte("a large page with a keyword"))
+			},
+			false,
+		},
+		{
+			"TestInvalidMaxBodySize",
+			task.Task{
+				Options: task.Options{
+					"keywords":      []any{"keyword"},
+					"max_body_size": -1,
+				},
+				Timeout:  10 * time.Second,
+				Alerter:  alert.NewDummyAlerter(),
+				Callback: nil,
+			},
+			true,
+			func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte("ok"))
+			},
+			true,
+		},
 	}
 
 	for _, tv := range tests {
@@ -112,7 +204,7 @@ func TestScrapeWebTask(t *testing.T) {
 
 			tv.Task.Options["url"] = testHttpServer.URL
 
-			err := WebScrapeTask(&tv.Task)
+			err := executeTask(WebScrape, &tv.Task)
 			if tv.ExpectedError {
 				assert.Error(t, err)
 			} else {
@@ -122,3 +214,205 @@ func TestScrapeWebTask(t *testing.T) {
 	}
 
 }
+
+func TestScrapeWebTask_StatusCodeError(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer testHttpServer.Close()
+
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":      testHttpServer.URL,
+			"keywords": []any{"keyword"},
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alert.NewDummyAlerter(),
+	})
+	var statusCodeError *task.StatusCodeError
+	assert.True(t, errors.As(err, &statusCodeError))
+	assert.Equal(t, http.StatusServiceUnavailable, statusCodeError.StatusCode)
+}
+
+func TestScrapeWebTask_MaxBodySize(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("first keyword, a lot of text and the second keyword"))
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":           testHttpServer.URL,
+			"keywords":      []any{"first", "second"},
+			"max_body_size": 20,
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"first"}}, alerter.Alerts())
+
+	// A keyword beyond the limit may be present, so its absence does not post an alert.
+	for _, options := range []task.Options{
+		{"keywords": []any{"second"}, "match": "none"},
+		{"condition": `"first" and not "second"`},
+	} {
+		options["url"] = testHttpServer.URL
+		options["max_body_size"] = 20
+		alerter := &recordingAlerter{}
+		err := executeTask(WebScrape, &task.Task{
+			Options: options,
+			Timeout: 10 * time.Second,
+			Alerter: alerter,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, alerter.Alerts())
+	}
+}
+
+func TestScrapeWebTask_DecodesContent(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Accept-Encoding") != acceptEncoding {
+			writer.WriteHeader(400)
+			return
+		}
+		page, _ := charmap.ISO8859_2.NewEncoder().Bytes([]byte("Locuri de muncă: Inginer Software, Bucureşti"))
+		writer.Header().Set("Content-Type", "text/html; charset=ISO-8859-2")
+		writer.Header().Set("Content-Encoding", "br")
+		brotliWriter := brotli.NewWriter(writer)
+		_, _ = brotliWriter.Write(page)
+		_ = brotliWriter.Close()
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":      testHttpServer.URL,
+			"keywords": []any{"Inginer Software, Bucureşti"},
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Inginer Software, Bucureşti"}}, alerter.Alerts())
+}
+
+func TestScrapeWebTask_Match(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("Graphics card - In stock"))
+	}))
+	defer testHttpServer.Close()
+
+	var tests = []struct {
+		TestName       string
+		Options        task.Options
+		ExpectedAlerts [][]string
+	}{
+		{
+			"NoneMatchesWhenTextDisappears",
+			task.Options{"keywords": []any{"Out of stock"}, "match": "none"},
+			[][]string{{"no keywords found: Out of stock"}},
+		},
+		{
+			"AllDoesNotMatch",
+			task.Options{"keywords": []any{"In stock", "Free shipping"}, "match": "all"},
+			nil,
+		},
+		{
+			"Condition",
+			task.Options{"condition": `"In stock" and not "Pre-order"`},
+			[][]string{{`condition matched: "In stock" and not "Pre-order"`, "found: In stock", "not found: Pre-order"}},
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			tv.Options["url"] = testHttpServer.URL
+			alerter := &recordingAlerter{}
+			err := executeTask(WebScrape, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: alerter,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tv.ExpectedAlerts, alerter.Alerts())
+		})
+	}
+}
+
+func TestScrapeWebTask_JavaScriptPages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shop", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`<div id="app"></div><script>fetch("/api/stock")</script>`))
+	})
+	mux.HandleFunc("/api/stock", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"status": "În stoc"}`))
+	})
+	// The prerender service stub renders the page it was given.
+	mux.HandleFunc("/render", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("<div id=\"app\">Rendered " + request.URL.Query().Get("url") + ": În stoc</div>"))
+	})
+	testHttpServer := httptest.NewServer(mux)
+	defer testHttpServer.Close()
+
+	var tests = []struct {
+		TestName string
+		Options  task.Options
+	}{
+		{"XhrUrl", task.Options{"xhr_url": testHttpServer.URL + "/api/stock"}},
+		{"XhrDiscover", task.Options{"xhr_discover": `fetch\("([^"]+)"\)`}},
+		{"PrerenderService", task.Options{"prerender_url": testHttpServer.URL + "/render?url={url}"}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			tv.Options["url"] = testHttpServer.URL + "/shop"
+			tv.Options["keywords"] = []any{"În stoc"}
+			alerter := &recordingAlerter{}
+			err := executeTask(WebScrape, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: alerter,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{{"În stoc"}}, alerter.Alerts())
+		})
+	}
+}
+
+func TestScrapeWebTask_JsonPage(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"name":"graphics card","available":true}`))
+	}))
+	defer testHttpServer.Close()
+
+	// Without an endpoint or json_path the raw JSON is searched.
+	alerter := &recordingAlerter{}
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":      testHttpServer.URL,
+			"keywords": []any{`"available":true`},
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{`"available":true`}}, alerter.Alerts())
+}
+
+func TestScrapeWebTask_InvalidXhrDiscover(t *testing.T) {
+	err := executeTask(WebScrape, &task.Task{
+		Options: task.Options{
+			"url":          "http://hotalert.test",
+			"keywords":     []any{"keyword"},
+			"xhr_discover": "fetch",
+		},
+		Timeout: 10 * time.Second,
+		Alerter: &recordingAlerter{},
+	})
+	assert.Error(t, err)
+}
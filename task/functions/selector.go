@@ -0,0 +1,164 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+)
+
+// selectorAttribute is an attribute condition of a cssSelector.
+type selectorAttribute struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// cssSelector is a simple CSS selector which matches elements by tag, id, classes and attributes,
+// such as `input[name=csrf_token]`, `span.price` or `#total`. Combinators are not supported.
+type cssSelector struct {
+	// source is the selector text.
+	source     string
+	tag        string
+	id         string
+	classes    []string
+	attributes []selectorAttribute
+}
+
+// parseSelector parses a simple CSS selector.
+func parseSelector(selector string) (*cssSelector, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.ContainsAny(selector, " >+~,") {
+		return nil, errors.New(fmt.Sprintf("invalid selector '%s', only simple selectors are supported", selector))
+	}
+
+	var parsed = cssSelector{source: selector}
+	i := 0
+	readName := func() string {
+		start := i
+		for i < len(selector) && strings.IndexByte("#.[", selector[i]) < 0 {
+			i += 1
+		}
+		return selector[start:i]
+	}
+	parsed.tag = strings.ToLower(readName())
+	for i < len(selector) {
+		switch selector[i] {
+		case '#':
+			i += 1
+			parsed.id = readName()
+		case '.':
+			i += 1
+			parsed.classes = append(parsed.classes, readName())
+		case '[':
+			end := strings.IndexByte(selector[i:], ']')
+			if end < 0 {
+				return nil, errors.New(fmt.Sprintf("invalid selector '%s', missing ]", selector))
+			}
+			attribute := selectorAttribute{name: selector[i+1 : i+end]}
+			if name, value, found := strings.Cut(attribute.name, "="); found {
+				attribute.name = name
+				attribute.value = strings.Trim(value, `"'`)
+				attribute.hasValue = true
+			}
+			parsed.attributes = append(parsed.attributes, attribute)
+			i += end + 1
+		default:
+			return nil, errors.New(fmt.Sprintf("invalid selector '%s', unexpected character '%c'", selector, selector[i]))
+		}
+	}
+	if parsed.id == "" && parsed.tag == "" && len(parsed.classes) == 0 && len(parsed.attributes) == 0 {
+		return nil, errors.New(fmt.Sprintf("invalid selector '%s'", selector))
+	}
+	return &parsed, nil
+}
+
+// String returns the selector text.
+func (s *cssSelector) String() string {
+	return s.source
+}
+
+// matches returns true if the node is an element matched by the selector.
+func (s *cssSelector) matches(node *html.Node) bool {
+	if node.Type != html.ElementNode || (s.tag != "" && s.tag != "*" && node.Data != s.tag) {
+		return false
+	}
+	if s.id != "" && nodeAttribute(node, "id") != s.id {
+		return false
+	}
+	nodeClasses := strings.Fields(nodeAttribute(node, "class"))
+	for _, class := range s.classes {
+		found := false
+		for _, nodeClass := range nodeClasses {
+			found = found || nodeClass == class
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, attribute := range s.attributes {
+		value, found := "", false
+		for _, nodeAttr := range node.Attr {
+			if nodeAttr.Key == attribute.name {
+				value, found = nodeAttr.Val, true
+			}
+		}
+		if !found || (attribute.hasValue && value != attribute.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// findFirst returns the first node matched by the selector, in document order, or nil.
+func (s *cssSelector) findFirst(node *html.Node) *html.Node {
+	if s.matches(node) {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := s.findFirst(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeAttribute returns the value of the attribute of the node or an empty string.
+func nodeAttribute(node *html.Node, name string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == name {
+			return attribute.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the text content of the node and its children.
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+	}
+	return text.String()
+}
+
+// selectValue parses the HTML document and returns the attribute of the first element matched by the selector,
+// or its text content if attribute is empty. It returns false if no element is matched.
+func selectValue(document io.Reader, selector *cssSelector, attribute string) (string, bool, error) {
+	root, err := html.Parse(document)
+	if err != nil {
+		return "", false, err
+	}
+	node := selector.findFirst(root)
+	if node == nil {
+		return "", false, nil
+	}
+	if attribute != "" {
+		return nodeAttribute(node, attribute), true, nil
+	}
+	return strings.TrimSpace(nodeText(node)), true, nil
+}
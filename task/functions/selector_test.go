@@ -0,0 +1,53 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var testSelectorDocument = `<html><body>
+<form><input type="hidden" name="csrf_token" value="token-123"><input name="user"></form>
+<div id="product" class="card featured">
+  <span class="price old">1.499,00 lei</span>
+  <span class="price">1.299,00 lei</span>
+</div>
+</body></html>`
+
+func Test_SelectValue(t *testing.T) {
+	var tests = []struct {
+		Selector      string
+		Attribute     string
+		ExpectedValue string
+		ExpectedFound bool
+	}{
+		{"input[name=csrf_token]", "value", "token-123", true},
+		{`input[name="csrf_token"][type=hidden]`, "value", "token-123", true},
+		{"span.price", "", "1.499,00 lei", true},
+		{"#product", "class", "card featured", true},
+		{"div.card.featured", "id", "product", true},
+		{"[name]", "name", "csrf_token", true},
+		{"span.missing", "", "", false},
+		{"input[name=missing]", "value", "", false},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.Selector, func(t *testing.T) {
+			selector, err := parseSelector(tv.Selector)
+			assert.NoError(t, err)
+			value, found, err := selectValue(strings.NewReader(testSelectorDocument), selector, tv.Attribute)
+			assert.NoError(t, err)
+			assert.Equal(t, tv.ExpectedFound, found)
+			assert.Equal(t, tv.ExpectedValue, value)
+		})
+	}
+}
+
+func Test_ParseSelector_Errors(t *testing.T) {
+	for _, selector := range []string{"", "div span", "div > span", "input[name=csrf", "a, b", "a[href]x", "a[href]:hover"} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := parseSelector(selector)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"net"
+	"strings"
+	"time"
+)
+
+// tcpCheckOptions are the options of TcpCheck.
+type tcpCheckOptions struct {
+	// Address is the host:port to connect to.
+	Address string `mapstructure:"address"`
+	// Banner is the optional text expected from the server after connecting.
+	Banner string `mapstructure:"banner"`
+}
+
+// Validate validates the options.
+func (o *tcpCheckOptions) Validate() error {
+	return requireOption("address", o.Address)
+}
+
+// TcpCheck connects to the given address and alerts when the connection fails or when the optional
+// banner is not found in the data sent by the server after connecting.
+var TcpCheck = task.NewFunction(
+	"Alerts when a TCP connection fails or the server does not send the expected banner.",
+	func() *tcpCheckOptions {
+		return &tcpCheckOptions{}
+	},
+	tcpCheck,
+)
+
+// tcpCheck executes the task given its decoded options.
+func tcpCheck(ctx context.Context, task *task.Task, options *tcpCheckOptions) error {
+	address, banner := options.Address, options.Banner
+
+	// The connection uses a shorter deadline than the task, so that a timeout still leaves time to post the alert.
+	connCtx, cancel := connectionContext(ctx)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(connCtx, "tcp", address)
+	if err != nil {
+		logging.SugaredLogger.Infof("Failed to connect to %s: %s", address, err)
+		task.Alerter.PostAlert(ctx, []string{
+			fmt.Sprintf("address: %s", address),
+			fmt.Sprintf("connection failed: %s", err),
+		})
+		return nil
+	}
+	defer conn.Close()
+
+	if banner == "" {
+		return nil
+	}
+
+	// Read until the banner is found, the server stops sending or the context is done.
+	if deadline, ok := connCtx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go func() {
+		select {
+		case <-connCtx.Done():
+			_ = conn.SetReadDeadline(time.Now())
+		case <-stopWatching:
+		}
+	}()
+	var received strings.Builder
+	var buffer = make([]byte, 1024)
+	for received.Len() < 64*1024 && !strings.Contains(received.String(), banner) {
+		n, err := conn.Read(buffer)
+		received.Write(buffer[:n])
+		if err != nil {
+			break
+		}
+	}
+	if !strings.Contains(received.String(), banner) {
+		task.Alerter.PostAlert(ctx, []string{
+			fmt.Sprintf("address: %s", address),
+			fmt.Sprintf("banner not found: %s", banner),
+			fmt.Sprintf("received: %q", received.String()),
+		})
+	}
+	return nil
+}
+
+// connectionContext returns a context for network I/O which expires before ctx, after four fifths of its remaining
+// time, so that the task can still post an alert when the connection times out.
+func connectionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, time.Now().Add(time.Until(deadline)*4/5))
+}
@@ -0,0 +1,61 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTcpCheckTask(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_8.9\r\n"))
+			_ = conn.Close()
+		}
+	}()
+
+	// Obtain an address which does not accept connections.
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddress := closedListener.Addr().String()
+	_ = closedListener.Close()
+
+	var tests = []struct {
+		TestName       string
+		Options        task.Options
+		ExpectedAlerts int
+		ExpectedError  bool
+	}{
+		{"Open", task.Options{"address": listener.Addr().String()}, 0, false},
+		{"BannerMatches", task.Options{"address": listener.Addr().String(), "banner": "OpenSSH"}, 0, false},
+		{"BannerDoesNotMatch", task.Options{"address": listener.Addr().String(), "banner": "nginx"}, 1, false},
+		{"Closed", task.Options{"address": closedAddress}, 1, false},
+		{"MissingAddress", task.Options{}, 0, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			alerter := &recordingAlerter{}
+			err := executeTask(TcpCheck, &task.Task{
+				Options: tv.Options,
+				Timeout: 5 * time.Second,
+				Alerter: alerter,
+			})
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, alerter.Alerts(), tv.ExpectedAlerts)
+		})
+	}
+}
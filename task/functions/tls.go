@@ -0,0 +1,123 @@
+package functions
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"net"
+	"os"
+	"time"
+)
+
+// tlsExpiryOptions are the options of TlsExpiry.
+type tlsExpiryOptions struct {
+	// Address is the host:port to connect to.
+	Address string `mapstructure:"address"`
+	// Days is the number of days before the expiry of the certificate when the alert is posted.
+	Days int `mapstructure:"days"`
+	// ServerName is the name used for SNI and verification. It defaults to the host of the address.
+	ServerName string `mapstructure:"server_name"`
+	// RootCA is the optional path to a PEM bundle with the root certificates used for verification.
+	RootCA string `mapstructure:"root_ca"`
+	// rootCAs are the certificates loaded from RootCA, nil means the system roots.
+	rootCAs *x509.CertPool
+}
+
+// Validate validates the options and loads the root certificates.
+func (o *tlsExpiryOptions) Validate() error {
+	if err := requireOption("address", o.Address); err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(o.Address)
+	if err != nil {
+		return errors.New(fmt.Sprintf("invalid task parameter address %s: %s", o.Address, err))
+	}
+	if o.ServerName == "" {
+		o.ServerName = host
+	}
+	o.rootCAs, err = loadRootCAs(o.RootCA)
+	return err
+}
+
+// TlsExpiry connects to the given address, inspects the presented certificate chain and alerts when
+// the leaf certificate expires within the configured number of days or when the chain fails verification.
+var TlsExpiry = task.NewFunction(
+	"Alerts when a TLS certificate expires soon or fails verification.",
+	func() *tlsExpiryOptions {
+		return &tlsExpiryOptions{Days: 14}
+	},
+	tlsExpiry,
+)
+
+// tlsExpiry executes the task given its decoded options.
+func tlsExpiry(ctx context.Context, task *task.Task, options *tlsExpiryOptions) error {
+	address, serverName, rootCAs := options.Address, options.ServerName, options.rootCAs
+
+	// Connect without verification, the chain is verified below so that we can report on it.
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to connect to %s: %s", address, err)
+		return err
+	}
+	defer conn.Close()
+
+	peerCertificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		return errors.New(fmt.Sprintf("no certificates presented by %s", address))
+	}
+	leaf := peerCertificates[0]
+
+	var reasons = make([]string, 0, 2)
+	intermediates := x509.NewCertPool()
+	for _, certificate := range peerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         rootCAs,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("verification failed: %s", err))
+	}
+	if leaf.NotAfter.Before(time.Now().AddDate(0, 0, options.Days)) {
+		reasons = append(reasons, fmt.Sprintf("expires in %d days", int(time.Until(leaf.NotAfter).Hours()/24)))
+	}
+
+	// If we have reasons post an alert.
+	if len(reasons) > 0 {
+		alertContext := append(reasons,
+			fmt.Sprintf("address: %s", address),
+			fmt.Sprintf("subject: %s", leaf.Subject),
+			fmt.Sprintf("issuer: %s", leaf.Issuer),
+			fmt.Sprintf("expiry: %s", leaf.NotAfter.Format(time.RFC3339)),
+		)
+		task.Alerter.PostAlert(ctx, alertContext)
+	}
+	return nil
+}
+
+// loadRootCAs loads the PEM bundle with root certificates from the given path.
+// It returns nil if the path is empty, which means the system roots are used.
+func loadRootCAs(rootCAPath string) (*x509.CertPool, error) {
+	if rootCAPath == "" {
+		return nil, nil
+	}
+	pemData, err := os.ReadFile(rootCAPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to read root_ca %s: %s", rootCAPath, err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, errors.New(fmt.Sprintf("no certificates found in root_ca %s", rootCAPath))
+	}
+	return pool, nil
+}
@@ -0,0 +1,100 @@
+package functions
+
+import (
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTlsExpiryTask(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer testServer.Close()
+	address := strings.TrimPrefix(testServer.URL, "https://")
+
+	// Write the test server certificate as a trusted root.
+	rootCAPath := filepath.Join(t.TempDir(), "root.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(rootCAPath, pemData, 0600))
+
+	var tests = []struct {
+		TestName       string
+		Options        task.Options
+		ExpectedAlerts int
+		ExpectedError  bool
+	}{
+		{
+			"ValidChain",
+			task.Options{"address": address, "root_ca": rootCAPath},
+			0,
+			false,
+		},
+		{
+			"ExpiresSoon",
+			task.Options{"address": address, "root_ca": rootCAPath, "days": 1000000},
+			1,
+			false,
+		},
+		{
+			"UntrustedChain",
+			task.Options{"address": address},
+			1,
+			false,
+		},
+		{
+			"InvalidAddress",
+			task.Options{"address": "localhost"},
+			0,
+			true,
+		},
+		{
+			"MissingAddress",
+			task.Options{},
+			0,
+			true,
+		},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			alerter := &recordingAlerter{}
+			err := executeTask(TlsExpiry, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: alerter,
+			})
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, alerter.Alerts(), tv.ExpectedAlerts)
+		})
+	}
+}
+
+func TestTlsExpiryTask_AlertContext(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer testServer.Close()
+
+	alerter := &recordingAlerter{}
+	err := executeTask(TlsExpiry, &task.Task{
+		Options: task.Options{"address": strings.TrimPrefix(testServer.URL, "https://")},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, alerter.Alerts(), 1)
+
+	alertContext := strings.Join(alerter.Alerts()[0], "\n")
+	assert.Contains(t, alertContext, "verification failed")
+	assert.Contains(t, alertContext, "subject: O=Acme Co")
+	assert.Contains(t, alertContext, "issuer: O=Acme Co")
+	assert.Contains(t, alertContext, "expiry: ")
+}
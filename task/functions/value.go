@@ -0,0 +1,281 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hotalert/logging"
+	"hotalert/task"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultValueHistorySize is the default number of values kept in the history of ValueTrackTask.
+const defaultValueHistorySize = 100
+
+// numberPattern matches a number which may contain thousands separators, such as 1.299,00 or 1 299.
+var numberPattern = regexp.MustCompile(`[-+]?[0-9][0-9.,'\s\x{00a0}\x{202f}]*`)
+
+// valueRecord is a value extracted by ValueTrackTask.
+type valueRecord struct {
+	Value float64
+	Time  time.Time
+}
+
+// valueTrackState is the state kept by ValueTrackTask between runs.
+type valueTrackState struct {
+	// History holds the extracted values, the oldest first.
+	History []valueRecord
+}
+
+// valueThresholds holds the conditions under which ValueTrack posts an alert.
+type valueThresholds struct {
+	// Below is the threshold which the value crosses when it drops below it.
+	Below *float64 `mapstructure:"below"`
+	// Above is the threshold which the value crosses when it rises above it.
+	Above *float64 `mapstructure:"above"`
+	// DropPercent is the minimum drop since the last run, in percent.
+	DropPercent *float64 `mapstructure:"drop_percent"`
+	// RisePercent is the minimum rise since the last run, in percent.
+	RisePercent *float64 `mapstructure:"rise_percent"`
+}
+
+// valueTrackOptions are the options of ValueTrack.
+type valueTrackOptions struct {
+	// Url is the url of the page.
+	Url string `mapstructure:"url"`
+	// Selector is the optional selector of the element which holds the number.
+	Selector string `mapstructure:"selector"`
+	// Attribute is the optional attribute of the selected element which holds the number.
+	Attribute string `mapstructure:"attribute"`
+	// Regex is the optional regex which finds the number in the selected text or in the page.
+	Regex string `mapstructure:"regex"`
+	// DecimalSeparator is the optional decimal separator, it is guessed when empty.
+	DecimalSeparator string `mapstructure:"decimal_separator"`
+	valueThresholds  `mapstructure:",squash"`
+	// HistorySize is the number of values kept in the history.
+	HistorySize int `mapstructure:"history_size"`
+	// MaxBodySize is the maximum number of response bytes read.
+	MaxBodySize        int `mapstructure:"max_body_size"`
+	httpRequestOptions `mapstructure:",squash"`
+	stateOptions       `mapstructure:",squash"`
+	// selector is the parsed Selector.
+	selector *cssSelector
+	// regex is the compiled Regex.
+	regex *regexp.Regexp
+}
+
+// Validate validates the options, parses the selector and compiles the regex.
+func (o *valueTrackOptions) Validate() error {
+	if err := requireOption("url", o.Url); err != nil {
+		return err
+	}
+	if o.Selector != "" {
+		var err error
+		o.selector, err = parseSelector(o.Selector)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid task parameter selector: %s", err))
+		}
+	}
+	if o.Regex != "" {
+		var err error
+		o.regex, err = regexp.Compile(o.Regex)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid task parameter regex %s: %s", o.Regex, err))
+		}
+	}
+	if o.selector == nil && o.regex == nil {
+		return errors.New("invalid task parameters, selector or regex are required")
+	}
+	if o.DecimalSeparator != "" && o.DecimalSeparator != "." && o.DecimalSeparator != "," {
+		return errors.New(fmt.Sprintf("invalid task parameter decimal_separator %s", o.DecimalSeparator))
+	}
+	if o.Below == nil && o.Above == nil && o.DropPercent == nil && o.RisePercent == nil {
+		return errors.New("invalid task parameters, one of below, above, drop_percent or rise_percent is required")
+	}
+	if o.HistorySize <= 0 {
+		return errors.New(fmt.Sprintf("invalid task parameter history_size %d, must be positive", o.HistorySize))
+	}
+	if o.MaxBodySize <= 0 {
+		return errors.New(fmt.Sprintf("invalid task parameter max_body_size %d, must be positive", o.MaxBodySize))
+	}
+	return o.httpRequestOptions.Validate()
+}
+
+// ValueTrack extracts a number, such as a price, from a web page and keeps its history in the task state.
+// The number is extracted with a selector and/or a regex and parsed according to its locale format, see
+// parseLocaleNumber. An alert with the old and new values is posted when the value crosses the below or above
+// thresholds or when it changed by more than drop_percent or rise_percent since the last run.
+var ValueTrack = task.NewFunction(
+	"Tracks a number, such as a price, on a web page and alerts on thresholds or relative changes.",
+	func() *valueTrackOptions {
+		return &valueTrackOptions{
+			HistorySize:        defaultValueHistorySize,
+			MaxBodySize:        defaultMaxBodySize,
+			httpRequestOptions: newHttpRequestOptions(),
+			stateOptions:       newStateOptions(),
+		}
+	},
+	valueTrack,
+)
+
+// valueTrack executes the task given its decoded options.
+func valueTrack(ctx context.Context, task *task.Task, options *valueTrackOptions) error {
+	targetUrl, requestOptions, store := options.Url, &options.httpRequestOptions, options.store()
+
+	page, _, err := fetchPage(ctx, requestOptions.client(httpClient(task)), requestOptions, targetUrl, int64(options.MaxBodySize))
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to fetch page: %s", err)
+		return err
+	}
+	value, err := extractValue(page, options.selector, options.Attribute, options.regex, options.DecimalSeparator)
+	if err != nil {
+		logging.SugaredLogger.Errorf("Failed to extract value from %s: %s", targetUrl, err)
+		return err
+	}
+
+	// Compare the value with the one from the last run.
+	stateKey := fmt.Sprintf("value_track %s %s %s %s", targetUrl, options.Selector, options.Attribute, options.Regex)
+	var trackState valueTrackState
+	if _, err := store.Load(stateKey, &trackState); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	var previous *valueRecord
+	if len(trackState.History) > 0 {
+		previous = &trackState.History[len(trackState.History)-1]
+	}
+	setOutput(ctx, "value", formatValue(value))
+	if previous != nil {
+		setOutput(ctx, "previous", formatValue(previous.Value))
+	}
+	if reasons := options.valueThresholds.Evaluate(previous, value); len(reasons) > 0 {
+		alertContext := append(reasons, fmt.Sprintf("url: %s", targetUrl))
+		if previous != nil {
+			alertContext = append(alertContext, fmt.Sprintf("old: %s", formatValue(previous.Value)))
+		}
+		alertContext = append(alertContext, fmt.Sprintf("new: %s", formatValue(value)))
+		task.Alerter.PostAlert(ctx, alertContext)
+	}
+
+	trackState.History = append(trackState.History, valueRecord{Value: value, Time: time.Now()})
+	if len(trackState.History) > options.HistorySize {
+		trackState.History = trackState.History[len(trackState.History)-options.HistorySize:]
+	}
+	if err := store.Save(stateKey, trackState); err != nil {
+		logging.SugaredLogger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Evaluate returns the reasons for posting an alert given the previous record, which is nil on the first run, and the
+// new value. The below and above thresholds only fire when they are crossed, so a value which stays below the
+// threshold is reported once.
+func (t *valueThresholds) Evaluate(previous *valueRecord, value float64) []string {
+	var reasons = make([]string, 0, 2)
+	if t.Below != nil && value < *t.Below && (previous == nil || previous.Value >= *t.Below) {
+		reasons = append(reasons, fmt.Sprintf("value %s is below %s", formatValue(value), formatValue(*t.Below)))
+	}
+	if t.Above != nil && value > *t.Above && (previous == nil || previous.Value <= *t.Above) {
+		reasons = append(reasons, fmt.Sprintf("value %s is above %s", formatValue(value), formatValue(*t.Above)))
+	}
+	if previous != nil && previous.Value != 0 {
+		change := (value - previous.Value) / previous.Value * 100
+		if previous.Value < 0 {
+			change = -change
+		}
+		if t.DropPercent != nil && change < 0 && -change >= *t.DropPercent {
+			reasons = append(reasons, fmt.Sprintf("value dropped %.1f%% since the last run", -change))
+		}
+		if t.RisePercent != nil && change > 0 && change >= *t.RisePercent {
+			reasons = append(reasons, fmt.Sprintf("value rose %.1f%% since the last run", change))
+		}
+	}
+	return reasons
+}
+
+// extractValue extracts the number from the page. The selector selects the element which holds the number, in its
+// text or attribute, and the regex finds the number in the selected text or in the whole page. The first group of
+// the regex is used if it has groups.
+func extractValue(page []byte, selector *cssSelector, attribute string, regex *regexp.Regexp, decimalSeparator string) (float64, error) {
+	text := string(page)
+	if selector != nil {
+		selected, found, err := selectValue(bytes.NewReader(page), selector, attribute)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			return 0, errors.New(fmt.Sprintf("no element matches %s", selector))
+		}
+		text = selected
+	}
+	if regex != nil {
+		match := regex.FindStringSubmatch(text)
+		if match == nil {
+			return 0, errors.New(fmt.Sprintf("no match for regex %s", regex))
+		}
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+	return parseLocaleNumber(text, decimalSeparator)
+}
+
+// parseLocaleNumber parses the first number in the text, such as `1.299,00 lei`, `$1,299.00` or `1 299`.
+// Spaces and apostrophes are thousands separators. If decimalSeparator is empty it is guessed: when both dots and
+// commas are used the last one is the decimal separator, and a single separator followed by exactly three digits
+// is a thousands separator.
+func parseLocaleNumber(text string, decimalSeparator string) (float64, error) {
+	rawNumber := numberPattern.FindString(text)
+	if rawNumber == "" {
+		return 0, errors.New(fmt.Sprintf("no number found in '%s'", text))
+	}
+	rawNumber = strings.TrimRight(rawNumber, ".,' \t\n\r\u00a0\u202f")
+	rawNumber = strings.NewReplacer(" ", "", "\t", "", "\n", "", "\r", "", "'", "", "\u00a0", "", "\u202f", "").Replace(rawNumber)
+
+	if decimalSeparator == "" {
+		lastDot, lastComma := strings.LastIndex(rawNumber, "."), strings.LastIndex(rawNumber, ",")
+		switch {
+		case lastDot >= 0 && lastComma >= 0:
+			decimalSeparator = "."
+			if lastComma > lastDot {
+				decimalSeparator = ","
+			}
+		case lastDot >= 0 || lastComma >= 0:
+			separator := "."
+			if lastComma >= 0 {
+				separator = ","
+			}
+			position := strings.LastIndex(rawNumber, separator)
+			if strings.Count(rawNumber, separator) == 1 && len(rawNumber)-position-1 != 3 {
+				decimalSeparator = separator
+			}
+		}
+	}
+
+	thousandsSeparator := ","
+	if decimalSeparator == "," {
+		thousandsSeparator = "."
+	}
+	normalized := strings.Replace(rawNumber, thousandsSeparator, "", -1)
+	if decimalSeparator == "" {
+		normalized = strings.Replace(normalized, ".", "", -1)
+	} else {
+		normalized = strings.Replace(normalized, decimalSeparator, ".", 1)
+	}
+	value, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid number '%s'", rawNumber))
+	}
+	return value, nil
+}
+
+// formatValue formats the value without trailing zeros.
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
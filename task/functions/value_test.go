@@ -0,0 +1,178 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hotalert/task"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_ParseLocaleNumber(t *testing.T) {
+	var tests = []struct {
+		TestName         string
+		Text             string
+		DecimalSeparator string
+		Expected         float64
+		ExpectError      bool
+	}{
+		{"RomanianPrice", "1.299,00 lei", "", 1299, false},
+		{"EnglishPrice", "$1,299.99", "", 1299.99, false},
+		{"SpaceThousands", "Preț: 12 499,90 Lei", "", 12499.9, false},
+		{"NoBreakSpaceThousands", "1 299 €", "", 1299, false},
+		{"SwissApostrophe", "CHF 1'299.50", "", 1299.5, false},
+		{"ThousandsDot", "1.299 lei", "", 1299, false},
+		{"ThousandsComma", "1,299", "", 1299, false},
+		{"DecimalComma", "12,5", "", 12.5, false},
+		{"DecimalDot", "99.9", "", 99.9, false},
+		{"ManyThousands", "1.234.567", "", 1234567, false},
+		{"ExplicitDecimalSeparator", "1,299", ",", 1.299, false},
+		{"ExplicitDecimalDot", "1.299", ".", 1.299, false},
+		{"TrailingDot", "Total 45.", "", 45, false},
+		{"Negative", "-3,5 °C", "", -3.5, false},
+		{"NoNumber", "Out of stock", "", 0, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			value, err := parseLocaleNumber(tv.Text, tv.DecimalSeparator)
+			if tv.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tv.Expected, value, 0.0001)
+		})
+	}
+}
+
+func Test_ExtractValue(t *testing.T) {
+	page := []byte(`<div class="product"><span class="price old">1.499,00 lei</span>` +
+		`<span class="price" data-price="1299.00">1.299,00 lei</span></div><script>var stock = 7;</script>`)
+
+	selector := func(selector string) *cssSelector {
+		parsed, err := parseSelector(selector)
+		assert.NoError(t, err)
+		return parsed
+	}
+
+	value, err := extractValue(page, selector("span.price"), "", nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1499.0, value)
+
+	value, err = extractValue(page, selector("span[data-price]"), "data-price", nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1299.0, value)
+
+	value, err = extractValue(page, nil, "", regexp.MustCompile(`stock = (\d+)`), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 7.0, value)
+
+	_, err = extractValue(page, selector("#total"), "", nil, "")
+	assert.Error(t, err)
+	_, err = extractValue(page, nil, "", regexp.MustCompile(`total = (\d+)`), "")
+	assert.Error(t, err)
+}
+
+func Test_ValueThresholds(t *testing.T) {
+	number := func(value float64) *float64 {
+		return &value
+	}
+
+	thresholds := &valueThresholds{Below: number(1200), DropPercent: number(10)}
+
+	assert.Equal(t, []string{"value 1199 is below 1200"}, thresholds.Evaluate(nil, 1199))
+	assert.Empty(t, thresholds.Evaluate(&valueRecord{Value: 1199}, 1150))
+	assert.Equal(t, []string{"value dropped 10.0% since the last run"}, thresholds.Evaluate(&valueRecord{Value: 1500}, 1350))
+	assert.Empty(t, thresholds.Evaluate(&valueRecord{Value: 1500}, 1400))
+	assert.Equal(t, []string{"value 1000 is below 1200", "value dropped 33.3% since the last run"},
+		thresholds.Evaluate(&valueRecord{Value: 1500}, 1000))
+
+	thresholds = &valueThresholds{Above: number(30), RisePercent: number(50)}
+	assert.Equal(t, []string{"value 31 is above 30"}, thresholds.Evaluate(&valueRecord{Value: 29}, 31))
+	assert.Equal(t, []string{"value rose 50.0% since the last run"}, thresholds.Evaluate(&valueRecord{Value: 10}, 15))
+	assert.Empty(t, thresholds.Evaluate(&valueRecord{Value: 15}, 10))
+}
+
+func TestValueTrackTask(t *testing.T) {
+	var price = "1.499,00 lei"
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`<span class="price">` + price + `</span>`))
+	}))
+	defer testHttpServer.Close()
+
+	alerter := &recordingAlerter{}
+	currentTask := &task.Task{
+		Options: task.Options{
+			"url":             testHttpServer.URL,
+			"selector":        "span.price",
+			"below":           1200,
+			"drop_percent":    10,
+			"state_directory": t.TempDir(),
+		},
+		Timeout: 10 * time.Second,
+		Alerter: alerter,
+	}
+
+	// The first run only records the value.
+	assert.NoError(t, executeTask(ValueTrack, currentTask))
+	assert.Empty(t, alerter.Alerts())
+
+	price = "1.399,00 lei"
+	assert.NoError(t, executeTask(ValueTrack, currentTask))
+	assert.Empty(t, alerter.Alerts())
+
+	price = "1.149,99 lei"
+	assert.NoError(t, executeTask(ValueTrack, currentTask))
+	assert.Equal(t, [][]string{{
+		"value 1149.99 is below 1200",
+		"value dropped 17.8% since the last run",
+		"url: " + testHttpServer.URL,
+		"old: 1399",
+		"new: 1149.99",
+	}}, alerter.Alerts())
+
+	// The value stays below the threshold, no new alert.
+	price = "1.139,99 lei"
+	assert.NoError(t, executeTask(ValueTrack, currentTask))
+	assert.Len(t, alerter.Alerts(), 1)
+}
+
+func TestValueTrackTask_Errors(t *testing.T) {
+	testHttpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(`<span class="price">Out of stock</span>`))
+	}))
+	defer testHttpServer.Close()
+
+	var tests = []struct {
+		TestName string
+		Options  task.Options
+	}{
+		{"MissingUrl", task.Options{"selector": "span.price", "below": 10}},
+		{"MissingSelectorAndRegex", task.Options{"url": testHttpServer.URL, "below": 10}},
+		{"MissingThresholds", task.Options{"url": testHttpServer.URL, "selector": "span.price"}},
+		{"InvalidThreshold", task.Options{"url": testHttpServer.URL, "selector": "span.price", "below": "10"}},
+		{"InvalidRegex", task.Options{"url": testHttpServer.URL, "regex": "(", "below": 10}},
+		{"InvalidSelector", task.Options{"url": testHttpServer.URL, "selector": "span[itemprop=price]:first-child", "below": 10}},
+		{"InvalidDecimalSeparator", task.Options{"url": testHttpServer.URL, "selector": "span.price", "below": 10, "decimal_separator": ";"}},
+		{"NoNumber", task.Options{"url": testHttpServer.URL, "selector": "span.price", "below": 10}},
+		{"NoElement", task.Options{"url": testHttpServer.URL, "selector": "#price", "below": 10}},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			tv.Options["state_directory"] = t.TempDir()
+			err := executeTask(ValueTrack, &task.Task{
+				Options: tv.Options,
+				Timeout: 10 * time.Second,
+				Alerter: &recordingAlerter{},
+			})
+			assert.Error(t, err)
+		})
+	}
+
+	// The selector is checked when the workload is loaded.
+	assert.Error(t, ValueTrack.ValidateOptions(task.Options{"url": testHttpServer.URL, "selector": "span x", "below": 10}))
+}
@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// outputPattern matches the ${tasks.<id>.<output>} placeholders which refer to the outputs of other tasks.
+var outputPattern = regexp.MustCompile(`\$\{tasks\.([^.}]+)\.([^}]+)}`)
+
+// Outputs holds the values produced by a task execution, which the tasks depending on it can use in their options.
+type Outputs struct {
+	mutex  sync.Mutex
+	values map[string]string
+}
+
+// NewOutputs returns new empty Outputs.
+func NewOutputs() *Outputs {
+	return &Outputs{values: make(map[string]string)}
+}
+
+// Set sets the value of an output.
+func (o *Outputs) Set(name string, value string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.values[name] = value
+}
+
+// Values returns a copy of the output values.
+func (o *Outputs) Values() map[string]string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var values = make(map[string]string, len(o.values))
+	for name, value := range o.values {
+		values[name] = value
+	}
+	return values
+}
+
+// outputsKey is the context key of the Outputs.
+type outputsKey struct{}
+
+// ContextWithOutputs returns a copy of the context which carries the outputs of the task execution.
+func ContextWithOutputs(ctx context.Context, outputs *Outputs) context.Context {
+	return context.WithValue(ctx, outputsKey{}, outputs)
+}
+
+// SetOutput sets an output of the task executed with the context. It does nothing if the context carries no Outputs.
+func SetOutput(ctx context.Context, name string, value string) {
+	if outputs, ok := ctx.Value(outputsKey{}).(*Outputs); ok {
+		outputs.Set(name, value)
+	}
+}
+
+// OutputReferences returns the ids of the tasks whose outputs are referenced by the options.
+func OutputReferences(options Options) []string {
+	var ids = make([]string, 0)
+	walkStrings(map[string]any(options), func(value string) {
+		for _, match := range outputPattern.FindAllStringSubmatch(value, -1) {
+			ids = append(ids, match[1])
+		}
+	})
+	return ids
+}
+
+// ExpandOutputs returns a copy of the options in which the ${tasks.<id>.<output>} placeholders are replaced with the
+// outputs of the tasks, given by task id. Other placeholders are kept. If escape is not nil, the outputs are escaped
+// with it before they are inserted, see OutputEscaper.
+func ExpandOutputs(options Options, outputs map[string]map[string]string, escape func(value string) string) (Options, error) {
+	var expandErr error
+	expanded := mapStrings(map[string]any(options), func(value string) string {
+		return outputPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+			match := outputPattern.FindStringSubmatch(placeholder)
+			value, found := outputs[match[1]][match[2]]
+			if !found && expandErr == nil {
+				expandErr = errors.New(fmt.Sprintf("task %s has no output %s", match[1], match[2]))
+			}
+			if escape != nil {
+				return escape(value)
+			}
+			return value
+		})
+	})
+	if expandErr != nil {
+		return nil, expandErr
+	}
+	return Options(expanded.(map[string]any)), nil
+}
+
+// walkStrings calls visit with each string in the value, which may contain maps and arrays.
+func walkStrings(value any, visit func(value string)) {
+	mapStrings(value, func(value string) string {
+		visit(value)
+		return value
+	})
+}
+
+// mapStrings returns a copy of the value in which each string is replaced by the result of mapping.
+func mapStrings(value any, mapping func(value string) string) any {
+	switch typedValue := value.(type) {
+	case string:
+		return mapping(typedValue)
+	case map[string]any:
+		var mapped = make(map[string]any, len(typedValue))
+		for key, item := range typedValue {
+			mapped[key] = mapStrings(item, mapping)
+		}
+		return mapped
+	case []any:
+		var mapped = make([]any, 0, len(typedValue))
+		for _, item := range typedValue {
+			mapped = append(mapped, mapStrings(item, mapping))
+		}
+		return mapped
+	case []string:
+		var mapped = make([]string, 0, len(typedValue))
+		for _, item := range typedValue {
+			mapped = append(mapped, mapping(item))
+		}
+		return mapped
+	}
+	return value
+}
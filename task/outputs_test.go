@@ -0,0 +1,64 @@
+package task
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_SetOutput(t *testing.T) {
+	outputs := NewOutputs()
+	ctx := ContextWithOutputs(context.Background(), outputs)
+	SetOutput(ctx, "value", "1299")
+	assert.Equal(t, map[string]string{"value": "1299"}, outputs.Values())
+
+	// Contexts without outputs are ignored.
+	SetOutput(context.Background(), "value", "1399")
+	assert.Equal(t, map[string]string{"value": "1299"}, outputs.Values())
+}
+
+func Test_OutputReferences(t *testing.T) {
+	references := OutputReferences(Options{
+		"url":     "https://shop.example.com/${tasks.search.path}?token=${env:TOKEN}",
+		"headers": map[string]any{"X-Session": "${tasks.login.session}"},
+		"steps":   []any{map[string]any{"body": "csrf=${csrf}"}},
+	})
+	assert.ElementsMatch(t, []string{"search", "login"}, references)
+	assert.Empty(t, OutputReferences(nil))
+}
+
+func Test_ExpandOutputs(t *testing.T) {
+	var outputs = map[string]map[string]string{
+		"search": {"path": "products/42"},
+		"login":  {"session": "abc"},
+	}
+	options := Options{
+		"url":      "https://shop.example.com/${tasks.search.path}",
+		"headers":  map[string]any{"X-Session": "${tasks.login.session}"},
+		"keywords": []string{"${tasks.search.path}"},
+		"steps":    []any{map[string]any{"body": "csrf=${csrf}"}},
+		"timeout":  10,
+	}
+
+	expanded, err := ExpandOutputs(options, outputs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Options{
+		"url":      "https://shop.example.com/products/42",
+		"headers":  map[string]any{"X-Session": "abc"},
+		"keywords": []string{"products/42"},
+		"steps":    []any{map[string]any{"body": "csrf=${csrf}"}},
+		"timeout":  10,
+	}, expanded)
+
+	// The options are not modified.
+	assert.Equal(t, "https://shop.example.com/${tasks.search.path}", options["url"])
+
+	_, err = ExpandOutputs(Options{"url": "${tasks.search.url}"}, outputs, nil)
+	assert.Error(t, err)
+
+	// Only the outputs are escaped.
+	expanded, err = ExpandOutputs(Options{"url": "${tasks.search.path}?${csrf}"}, outputs, strings.ToUpper)
+	assert.NoError(t, err)
+	assert.Equal(t, Options{"url": "PRODUCTS/42?${csrf}"}, expanded)
+}
@@ -0,0 +1,127 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// RetryOnTimeout retries tasks which timed out.
+	RetryOnTimeout = "timeout"
+	// RetryOn5xx retries tasks which failed with a 5xx http status code.
+	RetryOn5xx = "5xx"
+	// RetryOnNetwork retries tasks which failed with a network error, such as a failed DNS lookup or a refused
+	// connection.
+	RetryOnNetwork = "network"
+)
+
+// StatusCodeError is the error returned by task functions when a response has an unexpected http status code.
+type StatusCodeError struct {
+	// Message describes the failed request.
+	Message string
+	// StatusCode is the status code of the response.
+	StatusCode int
+}
+
+// Error returns the message and the status code.
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("%s, status code %d", e.Message, e.StatusCode)
+}
+
+// RetryPolicy decides whether a failed task is executed again.
+type RetryPolicy struct {
+	// Retries is the maximum number of times a failed task is retried, zero disables retries.
+	Retries int
+	// Backoff is the wait before the first retry, it doubles after each retry.
+	Backoff time.Duration
+	// RetryOn are the kinds of errors which are retried, any of RetryOnTimeout, RetryOn5xx and RetryOnNetwork.
+	// All kinds are retried when it is empty.
+	RetryOn []string
+}
+
+// Validate returns an error if the policy is not valid.
+func (p *RetryPolicy) Validate() error {
+	if p.Retries < 0 {
+		return errors.New(fmt.Sprintf("invalid retries %d, cannot be negative", p.Retries))
+	}
+	if p.Backoff < 0 {
+		return errors.New(fmt.Sprintf("invalid retry backoff %s, cannot be negative", p.Backoff))
+	}
+	for _, kind := range p.RetryOn {
+		switch kind {
+		case RetryOnTimeout, RetryOn5xx, RetryOnNetwork:
+		default:
+			return errors.New(fmt.Sprintf("invalid retry_on '%s', expected timeout, 5xx or network", kind))
+		}
+	}
+	return nil
+}
+
+// ShouldRetry returns true if the task should be executed again after the given attempt, starting from 1, failed
+// with err.
+func (p *RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	if err == nil || attempt > p.Retries {
+		return false
+	}
+	kind := ErrorKind(err)
+	if kind == "" {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, retryOn := range p.RetryOn {
+		if retryOn == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// BackoffBefore returns the wait before the given retry, starting from 1.
+func (p *RetryPolicy) BackoffBefore(retry int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < retry; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+// KindError is an error of a known kind, it keeps the kind of errors received from another process.
+type KindError struct {
+	// Message is the message of the error.
+	Message string
+	// Kind is the kind of the error, see ErrorKind.
+	Kind string
+}
+
+func (e *KindError) Error() string {
+	return e.Message
+}
+
+// ErrorKind returns the kind of the error, one of RetryOnTimeout, RetryOn5xx or RetryOnNetwork, or an empty string
+// if the error is of neither kind.
+func ErrorKind(err error) string {
+	var kindError *KindError
+	var statusCodeError *StatusCodeError
+	var netError net.Error
+	switch {
+	case errors.As(err, &kindError):
+		return kindError.Kind
+	case errors.Is(err, context.DeadlineExceeded):
+		return RetryOnTimeout
+	case errors.As(err, &statusCodeError):
+		if statusCodeError.StatusCode >= 500 && statusCodeError.StatusCode <= 599 {
+			return RetryOn5xx
+		}
+	case errors.As(err, &netError):
+		if netError.Timeout() {
+			return RetryOnTimeout
+		}
+		return RetryOnNetwork
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_StatusCodeError(t *testing.T) {
+	var err error = &StatusCodeError{Message: "Failed to query website", StatusCode: 503}
+	assert.Equal(t, "Failed to query website, status code 503", err.Error())
+}
+
+func Test_RetryPolicy_ShouldRetry(t *testing.T) {
+	var dnsError = &url.Error{Op: "Get", URL: "https://hotalert.test", Err: &net.DNSError{Err: "no such host", Name: "hotalert.test"}}
+	var timeoutError = fmt.Errorf("task timed out after 1s: %w", context.DeadlineExceeded)
+	var tests = []struct {
+		TestName      string
+		RetryPolicy   RetryPolicy
+		Attempt       int
+		Error         error
+		ExpectedRetry bool
+	}{
+		{"NoError", RetryPolicy{Retries: 1}, 1, nil, false},
+		{"NoRetries", RetryPolicy{}, 1, timeoutError, false},
+		{"Timeout", RetryPolicy{Retries: 1}, 1, timeoutError, true},
+		{"NetworkTimeout", RetryPolicy{Retries: 1, RetryOn: []string{RetryOnTimeout}}, 1, &net.DNSError{IsTimeout: true}, true},
+		{"ServerError", RetryPolicy{Retries: 1}, 1, &StatusCodeError{StatusCode: 502}, true},
+		{"ClientError", RetryPolicy{Retries: 1}, 1, &StatusCodeError{StatusCode: 404}, false},
+		{"NetworkError", RetryPolicy{Retries: 1}, 1, dnsError, true},
+		{"OtherError", RetryPolicy{Retries: 1}, 1, errors.New("keyword not found"), false},
+		{"KindError", RetryPolicy{Retries: 1, RetryOn: []string{RetryOnNetwork}}, 1, &KindError{Message: "no such host", Kind: RetryOnNetwork}, true},
+		{"KindErrorWithoutKind", RetryPolicy{Retries: 1}, 1, &KindError{Message: "keyword not found"}, false},
+		{"Cancelled", RetryPolicy{Retries: 1}, 1, fmt.Errorf("task cancelled: %w", context.Canceled), false},
+		{"RetriesExhausted", RetryPolicy{Retries: 2}, 3, timeoutError, false},
+		{"LastRetry", RetryPolicy{Retries: 2}, 2, timeoutError, true},
+		{"RetryOnMatches", RetryPolicy{Retries: 1, RetryOn: []string{RetryOn5xx}}, 1, &StatusCodeError{StatusCode: 500}, true},
+		{"RetryOnDoesNotMatch", RetryPolicy{Retries: 1, RetryOn: []string{RetryOn5xx}}, 1, dnsError, false},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			assert.Equal(t, tv.ExpectedRetry, tv.RetryPolicy.ShouldRetry(tv.Attempt, tv.Error))
+		})
+	}
+}
+
+func Test_RetryPolicy_BackoffBefore(t *testing.T) {
+	var retryPolicy = RetryPolicy{Retries: 3, Backoff: time.Second}
+	assert.Equal(t, time.Second, retryPolicy.BackoffBefore(1))
+	assert.Equal(t, 2*time.Second, retryPolicy.BackoffBefore(2))
+	assert.Equal(t, 4*time.Second, retryPolicy.BackoffBefore(3))
+}
+
+func Test_RetryPolicy_Validate(t *testing.T) {
+	var tests = []struct {
+		TestName      string
+		RetryPolicy   RetryPolicy
+		ExpectedError bool
+	}{
+		{"Empty", RetryPolicy{}, false},
+		{"Valid", RetryPolicy{Retries: 2, Backoff: time.Second, RetryOn: []string{"timeout", "5xx", "network"}}, false},
+		{"NegativeRetries", RetryPolicy{Retries: -1}, true},
+		{"NegativeBackoff", RetryPolicy{Backoff: -time.Second}, true},
+		{"InvalidRetryOn", RetryPolicy{RetryOn: []string{"4xx"}}, true},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			err := tv.RetryPolicy.Validate()
+			if tv.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
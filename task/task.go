@@ -3,6 +3,8 @@ package task
 import (
 	"fmt"
 	"hotalert/alert"
+	"hotalert/httpclient"
+	"net/http"
 	"time"
 )
 
@@ -14,16 +16,35 @@ type Options map[string]any
 
 // Task represents the context of a task.
 type Task struct {
+	// Id is the optional id of the task, which other tasks use to depend on it.
+	Id string
+	// DependsOn are the ids of the tasks which must succeed before the task is executed.
+	// The options of the task may refer to their outputs as ${tasks.<id>.<output>}.
+	DependsOn []string
 	// ExecutionFuncName is the function name associated with this task.
 	ExecutionFuncName string
 	// Options are the option given to the task.
 	Options Options `mapstructure:"options"`
-	// Timeout is the timeout for the task.
+	// Timeout is the timeout for the task, enforced by the executor. Zero or negative means no timeout.
 	Timeout time.Duration `mapstructure:"timeout"`
+	// Priority is the priority of the task in the executor queue, tasks with a higher priority are executed first.
+	Priority int
+	// Workload is the name of the workload the task belongs to. The executor takes tasks of the same priority in
+	// turns from each workload.
+	Workload string
+	// Retry is the policy which decides whether the executor retries the task when it fails.
+	Retry RetryPolicy
 	// Alerter is the alerter that will be called when task is completed.
 	Alerter alert.Alerter `mapstructure:"alerter"`
-	// Callback is an optional function that will be called when task is completed. (Not implemented)
+	// Callback is an optional function that will be called by the executor with the result when task is completed,
+	// including when the task was not executed.
 	Callback *Callback
+	// HttpClient is the optional http client used by task functions which issue http requests.
+	// Task functions fall back to http.DefaultClient when it is nil.
+	HttpClient *http.Client
+	// HttpOptions are the optional options which HttpClient was built with, they are used to build the http client of
+	// tasks executed in another process.
+	HttpOptions *httpclient.Options
 }
 
 // NewTask returns a new task instance.
@@ -46,6 +67,10 @@ type Result struct {
 	InitialTask *Task
 	// error is the error of the task.
 	error error
+	// attempts is the number of times the task was executed.
+	attempts int
+	// outputs are the outputs of the task execution.
+	outputs map[string]string
 }
 
 // NewResult represents the result of a task.
@@ -61,6 +86,26 @@ func (r *Result) SetError(err error) {
 	r.error = err
 }
 
+// SetAttempts sets the number of times the task was executed.
+func (r *Result) SetAttempts(attempts int) {
+	r.attempts = attempts
+}
+
+// Attempts returns the number of times the task was executed, retries included.
+func (r *Result) Attempts() int {
+	return r.attempts
+}
+
+// SetOutputs sets the outputs of the task execution.
+func (r *Result) SetOutputs(outputs map[string]string) {
+	r.outputs = outputs
+}
+
+// Outputs returns the outputs of the task execution, see SetOutput.
+func (r *Result) Outputs() map[string]string {
+	return r.outputs
+}
+
 // Error returns the error encountered during the execution of the task.
 // Error returns null if the task had no errors and was completed.
 func (r *Result) Error() error {
@@ -30,6 +30,8 @@ func Test_NewResult(t *testing.T) {
 	testError := errors.New("test error")
 	var result = NewResult(task)
 	result.SetError(testError)
+	result.SetAttempts(2)
+	result.SetOutputs(map[string]string{"value": "1299"})
 	assert.Equal(t, Result{
 		InitialTask: &Task{
 			ExecutionFuncName: "web_scrape",
@@ -40,6 +42,10 @@ func Test_NewResult(t *testing.T) {
 			Alerter:  alert.NewDummyAlerter(),
 			Callback: nil,
 		},
-		error: testError,
+		error:    testError,
+		attempts: 2,
+		outputs:  map[string]string{"value": "1299"},
 	}, *result)
+	assert.Equal(t, 2, result.Attempts())
+	assert.Equal(t, map[string]string{"value": "1299"}, result.Outputs())
 }
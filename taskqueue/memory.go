@@ -0,0 +1,132 @@
+package taskqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryQueue is a Queue held in memory, shared by the producers and consumers of a single process.
+type MemoryQueue struct {
+	mutex  sync.Mutex
+	queues map[string][]*memoryMessage
+	// changed is closed and replaced when a message is pushed, to wake up the waiting consumers.
+	changed chan struct{}
+	closed  bool
+}
+
+// memoryMessage is a message of a MemoryQueue.
+type memoryMessage struct {
+	Message
+	// leasedUntil is the time at which the lease of the message expires, it is zero if the message was not popped.
+	leasedUntil time.Time
+}
+
+// NewMemoryQueue returns a new empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		queues:  make(map[string][]*memoryMessage),
+		changed: make(chan struct{}),
+	}
+}
+
+// Push adds a message to the named queue.
+func (q *MemoryQueue) Push(ctx context.Context, queue string, body []byte) error {
+	id, err := newMessageId()
+	if err != nil {
+		return err
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.queues[queue] = append(q.queues[queue], &memoryMessage{Message: Message{Id: id, Body: body}})
+	close(q.changed)
+	q.changed = make(chan struct{})
+	return nil
+}
+
+// Pop waits until a message of the named queue is available and leases it for the given duration.
+// The messages are delivered in the order in which they were pushed.
+func (q *MemoryQueue) Pop(ctx context.Context, queue string, lease time.Duration) (*Message, error) {
+	for {
+		q.mutex.Lock()
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, ErrQueueClosed
+		}
+		now := time.Now()
+		// The earliest lease expiry, when a message becomes available again.
+		var nextExpiry time.Time
+		for _, message := range q.queues[queue] {
+			if !message.leasedUntil.After(now) {
+				message.leasedUntil = now.Add(lease)
+				message.Deliveries += 1
+				popped := message.Message
+				q.mutex.Unlock()
+				return &popped, nil
+			}
+			if nextExpiry.IsZero() || message.leasedUntil.Before(nextExpiry) {
+				nextExpiry = message.leasedUntil
+			}
+		}
+		changed := q.changed
+		q.mutex.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !nextExpiry.IsZero() {
+			timer = time.NewTimer(nextExpiry.Sub(now))
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Extend renews the lease of a popped message for the given duration from now.
+func (q *MemoryQueue) Extend(ctx context.Context, queue string, id string, lease time.Duration) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, message := range q.queues[queue] {
+		if message.Id == id {
+			message.leasedUntil = time.Now().Add(lease)
+			return nil
+		}
+	}
+	return ErrUnknownMessage
+}
+
+// Ack removes a popped message from the queue.
+func (q *MemoryQueue) Ack(ctx context.Context, queue string, id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, message := range q.queues[queue] {
+		if message.Id == id {
+			q.queues[queue] = append(q.queues[queue][:i], q.queues[queue][i+1:]...)
+			return nil
+		}
+	}
+	return ErrUnknownMessage
+}
+
+// Close makes the pending and future operations of the queue fail with ErrQueueClosed.
+func (q *MemoryQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.changed)
+	}
+	return nil
+}
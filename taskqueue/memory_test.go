@@ -0,0 +1,28 @@
+package taskqueue
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_MemoryQueue(t *testing.T) {
+	testQueue(t, func(t *testing.T) Queue {
+		return NewMemoryQueue()
+	})
+}
+
+func Test_MemoryQueue_Close(t *testing.T) {
+	queue := NewMemoryQueue()
+	var popErr = make(chan error)
+	go func() {
+		_, err := queue.Pop(context.Background(), "tasks", time.Minute)
+		popErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, queue.Close())
+	assert.ErrorIs(t, <-popErr, ErrQueueClosed)
+	assert.ErrorIs(t, queue.Push(context.Background(), "tasks", []byte("task")), ErrQueueClosed)
+}
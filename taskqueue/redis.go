@@ -0,0 +1,370 @@
+package taskqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisPoolSize is the maximum number of idle connections kept by a RedisQueue.
+const redisPoolSize = 16
+
+// redisDialTimeout is the timeout of the connections to the Redis server.
+const redisDialTimeout = 10 * time.Second
+
+// RedisQueue is a Queue stored in a Redis server, or in any server which supports the same commands.
+//
+// The messages of a queue are kept in the <queue>:messages hash by id and their deliveries in the <queue>:deliveries
+// hash. The <queue>:ready sorted set holds the ids of the messages scored by the time at which they can be popped,
+// which is the push time until they are popped and the end of the lease afterwards. A message is popped by setting
+// its score to the end of its lease in a transaction which fails if another consumer changed the set meanwhile.
+type RedisQueue struct {
+	address  string
+	password string
+	database int
+	// PollInterval is the interval at which Pop checks for available messages.
+	PollInterval time.Duration
+	// pool holds the idle connections.
+	pool chan *redisConn
+}
+
+// DialRedis connects to the Redis server given by the url, redis://[:password@]host[:port][/database].
+func DialRedis(redisUrl *url.URL) (*RedisQueue, error) {
+	q := &RedisQueue{
+		address:      redisUrl.Host,
+		PollInterval: 100 * time.Millisecond,
+		pool:         make(chan *redisConn, redisPoolSize),
+	}
+	if redisUrl.Port() == "" {
+		q.address = net.JoinHostPort(redisUrl.Hostname(), "6379")
+	}
+	if password, ok := redisUrl.User.Password(); ok {
+		q.password = password
+	}
+	if database := strings.TrimPrefix(redisUrl.Path, "/"); database != "" {
+		var err error
+		q.database, err = strconv.Atoi(database)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid redis database '%s'", database))
+		}
+	}
+
+	// Check the connection now rather than on the first operation.
+	conn, err := q.dial()
+	if err != nil {
+		return nil, err
+	}
+	q.put(conn, nil)
+	return q, nil
+}
+
+// Push adds a message to the named queue.
+func (q *RedisQueue) Push(ctx context.Context, queue string, body []byte) error {
+	id, err := newMessageId()
+	if err != nil {
+		return err
+	}
+	_, err = q.transaction(ctx, nil, [][]string{
+		{"HSET", queue + ":messages", id, string(body)},
+		{"ZADD", queue + ":ready", redisTime(time.Now()), id},
+	})
+	return err
+}
+
+// Pop waits until a message of the named queue is available and leases it for the given duration.
+// The messages are delivered in the order in which they can be popped.
+func (q *RedisQueue) Pop(ctx context.Context, queue string, lease time.Duration) (*Message, error) {
+	for {
+		now := time.Now()
+		conn, err := q.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := conn.do("WATCH", queue+":ready")
+		if err == nil {
+			reply, err = conn.do("ZRANGEBYSCORE", queue+":ready", "-inf", redisTime(now), "LIMIT", "0", "1")
+		}
+		if err != nil {
+			// The connection may still watch the key.
+			_ = conn.Close()
+			return nil, contextError(ctx, err)
+		}
+		ids, _ := reply.([]any)
+		if len(ids) == 0 {
+			_, err = conn.do("UNWATCH")
+			q.put(conn, err)
+			if err != nil {
+				return nil, contextError(ctx, err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(q.PollInterval):
+			}
+			continue
+		}
+
+		id, _ := ids[0].(string)
+		results, err := q.transaction(ctx, conn, [][]string{
+			{"ZADD", queue + ":ready", redisTime(now.Add(lease)), id},
+			{"HINCRBY", queue + ":deliveries", id, "1"},
+			{"HGET", queue + ":messages", id},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The transaction is aborted when another consumer popped a message or a message was pushed, try again.
+		if results == nil {
+			continue
+		}
+		deliveries, _ := results[1].(int64)
+		body, ok := results[2].(string)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("message %s of queue %s has no body", id, queue))
+		}
+		return &Message{Id: id, Body: []byte(body), Deliveries: int(deliveries)}, nil
+	}
+}
+
+// Extend renews the lease of a popped message for the given duration from now.
+func (q *RedisQueue) Extend(ctx context.Context, queue string, id string, lease time.Duration) error {
+	conn, err := q.get(ctx)
+	if err != nil {
+		return err
+	}
+	reply, err := conn.do("ZADD", queue+":ready", "XX", "CH", redisTime(time.Now().Add(lease)), id)
+	// The score is not changed when the lease ends at the same millisecond, check that the message exists.
+	if err == nil && reply != int64(1) {
+		reply, err = conn.do("ZSCORE", queue+":ready", id)
+	}
+	q.put(conn, err)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if reply == nil {
+		return ErrUnknownMessage
+	}
+	return nil
+}
+
+// Ack removes a popped message from the queue.
+func (q *RedisQueue) Ack(ctx context.Context, queue string, id string) error {
+	results, err := q.transaction(ctx, nil, [][]string{
+		{"ZREM", queue + ":ready", id},
+		{"HDEL", queue + ":messages", id},
+		{"HDEL", queue + ":deliveries", id},
+	})
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 || results[0] != int64(1) {
+		return ErrUnknownMessage
+	}
+	return nil
+}
+
+// Close closes the idle connections to the Redis server.
+func (q *RedisQueue) Close() error {
+	for {
+		select {
+		case conn := <-q.pool:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// transaction executes the commands in a MULTI and EXEC transaction on the connection, or on a connection of the
+// pool if it is nil, and returns their replies. It returns nil replies if the transaction was aborted because a
+// watched key changed.
+func (q *RedisQueue) transaction(ctx context.Context, conn *redisConn, commands [][]string) ([]any, error) {
+	if conn == nil {
+		var err error
+		conn, err = q.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := conn.do("MULTI")
+	for _, command := range commands {
+		if err != nil {
+			break
+		}
+		_, err = conn.do(command...)
+	}
+	var reply any
+	if err == nil {
+		reply, err = conn.do("EXEC")
+	}
+	if err != nil {
+		// The connection may still be in the transaction.
+		_ = conn.Close()
+		return nil, contextError(ctx, err)
+	}
+	q.put(conn, nil)
+	results, _ := reply.([]any)
+	return results, nil
+}
+
+// get returns an idle connection of the pool, or a new connection. It returns the context error if the context is
+// done, the deadline of the context would make the operations of the connection fail with a timeout error.
+func (q *RedisQueue) get(ctx context.Context) (*redisConn, error) {
+	if err := contextError(ctx, nil); err != nil {
+		return nil, err
+	}
+	var conn *redisConn
+	select {
+	case conn = <-q.pool:
+	default:
+		var err error
+		conn, err = q.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return conn, nil
+}
+
+// put returns the connection to the pool, unless the operation failed with a connection error or the pool is full.
+func (q *RedisQueue) put(conn *redisConn, err error) {
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		_ = conn.Close()
+		return
+	}
+	select {
+	case q.pool <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+// dial opens a new connection, authenticated and with the database selected.
+func (q *RedisQueue) dial() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", q.address, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if q.password != "" {
+		_, err = conn.do("AUTH", q.password)
+	}
+	if err == nil && q.database != 0 {
+		_, err = conn.do("SELECT", strconv.Itoa(q.database))
+	}
+	if err == nil {
+		_, err = conn.do("PING")
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// contextError returns the context error if the context is done or its deadline passed, the operation then failed
+// because of the connection deadline, which is the context deadline. Otherwise it returns err.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	// The deadline may pass before the context is done.
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// redisTime returns the time as a sorted set score, in milliseconds since the epoch.
+func redisTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// redisError is an error reply of the Redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn is a connection to a Redis server which speaks the RESP protocol.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// do sends the command and returns its reply: a string, an int64, a []any, nil for null replies, or a redisError.
+func (c *redisConn) do(args ...string) (any, error) {
+	var command strings.Builder
+	command.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		command.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+	if _, err := io.WriteString(c.Conn, command.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a reply of the server.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New(fmt.Sprintf("invalid redis reply %q", line))
+	}
+	kind, value := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, redisError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		var items = make([]any, 0, length)
+		for i := 0; i < length; i++ {
+			item, err := c.readReply()
+			// Error replies of the commands of a transaction are part of the EXEC reply.
+			var replyErr redisError
+			if err != nil && !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			if err != nil {
+				item = err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, errors.New(fmt.Sprintf("invalid redis reply %q", line))
+}
@@ -0,0 +1,376 @@
+package taskqueue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redisStandIn is a local server which implements the Redis commands used by RedisQueue, with the same replies.
+type redisStandIn struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	sets     map[string]map[string]float64
+	hashes   map[string]map[string]string
+	// versions counts the changes of each key, for WATCH.
+	versions map[string]int
+}
+
+// Reply types of the stand-in which are not strings, integers or arrays.
+type (
+	redisStatus    string
+	redisNilArray  struct{}
+	redisErrReply  string
+	redisStandConn struct {
+		watched map[string]int
+		queued  [][]string
+		multi   bool
+	}
+)
+
+// newRedisStandIn starts a stand-in server, which is closed at the end of the test.
+func newRedisStandIn(t *testing.T) *redisStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &redisStandIn{
+		listener: listener,
+		sets:     make(map[string]map[string]float64),
+		hashes:   make(map[string]map[string]string),
+		versions: make(map[string]int),
+	}
+	go server.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return server
+}
+
+func (s *redisStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *redisStandIn) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	state := &redisStandConn{}
+	for {
+		command, err := readStandInCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, encodeStandInReply(s.handle(state, command))); err != nil {
+			return
+		}
+	}
+}
+
+// readStandInCommand reads a command sent as an array of bulk strings.
+func readStandInCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	var command = make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		command = append(command, string(data[:length]))
+	}
+	return command, nil
+}
+
+func encodeStandInReply(reply any) string {
+	switch typedReply := reply.(type) {
+	case redisStatus:
+		return fmt.Sprintf("+%s\r\n", typedReply)
+	case redisErrReply:
+		return fmt.Sprintf("-%s\r\n", typedReply)
+	case int64:
+		return fmt.Sprintf(":%d\r\n", typedReply)
+	case string:
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(typedReply), typedReply)
+	case redisNilArray:
+		return "*-1\r\n"
+	case []any:
+		var encoded strings.Builder
+		encoded.WriteString(fmt.Sprintf("*%d\r\n", len(typedReply)))
+		for _, item := range typedReply {
+			encoded.WriteString(encodeStandInReply(item))
+		}
+		return encoded.String()
+	}
+	return "$-1\r\n"
+}
+
+// handle handles a command of a connection, queueing it in transactions.
+func (s *redisStandIn) handle(state *redisStandConn, command []string) any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	name := strings.ToUpper(command[0])
+	switch {
+	case name == "WATCH":
+		if state.watched == nil {
+			state.watched = make(map[string]int)
+		}
+		for _, key := range command[1:] {
+			state.watched[key] = s.versions[key]
+		}
+		return redisStatus("OK")
+	case name == "UNWATCH":
+		state.watched = nil
+		return redisStatus("OK")
+	case name == "MULTI":
+		state.multi = true
+		return redisStatus("OK")
+	case name == "EXEC":
+		queued, watched := state.queued, state.watched
+		state.multi, state.queued, state.watched = false, nil, nil
+		for key, version := range watched {
+			if s.versions[key] != version {
+				return redisNilArray{}
+			}
+		}
+		var replies = make([]any, 0, len(queued))
+		for _, queuedCommand := range queued {
+			replies = append(replies, s.execute(queuedCommand))
+		}
+		return replies
+	case state.multi:
+		state.queued = append(state.queued, command)
+		return redisStatus("QUEUED")
+	}
+	return s.execute(command)
+}
+
+// execute executes a data command.
+func (s *redisStandIn) execute(command []string) any {
+	args := command[1:]
+	switch strings.ToUpper(command[0]) {
+	case "PING":
+		return redisStatus("PONG")
+	case "AUTH", "SELECT":
+		return redisStatus("OK")
+	case "HSET":
+		hash := s.hash(args[0])
+		_, exists := hash[args[1]]
+		hash[args[1]] = args[2]
+		s.versions[args[0]] += 1
+		if exists {
+			return int64(0)
+		}
+		return int64(1)
+	case "HGET":
+		if value, ok := s.hashes[args[0]][args[1]]; ok {
+			return value
+		}
+		return nil
+	case "HDEL":
+		_, exists := s.hashes[args[0]][args[1]]
+		if !exists {
+			return int64(0)
+		}
+		delete(s.hashes[args[0]], args[1])
+		s.versions[args[0]] += 1
+		return int64(1)
+	case "HINCRBY":
+		hash := s.hash(args[0])
+		increment, _ := strconv.ParseInt(args[2], 10, 64)
+		value, _ := strconv.ParseInt(hash[args[1]], 10, 64)
+		hash[args[1]] = strconv.FormatInt(value+increment, 10)
+		s.versions[args[0]] += 1
+		return value + increment
+	case "ZADD":
+		var onlyExisting, countChanged bool
+		for len(args) > 1 && (strings.ToUpper(args[1]) == "XX" || strings.ToUpper(args[1]) == "CH") {
+			onlyExisting = onlyExisting || strings.ToUpper(args[1]) == "XX"
+			countChanged = countChanged || strings.ToUpper(args[1]) == "CH"
+			args = append(args[:1], args[2:]...)
+		}
+		set := s.set(args[0])
+		score, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return redisErrReply("ERR value is not a valid float")
+		}
+		previous, exists := set[args[2]]
+		if onlyExisting && !exists {
+			return int64(0)
+		}
+		set[args[2]] = score
+		if !exists || previous != score {
+			s.versions[args[0]] += 1
+		}
+		if !exists || (countChanged && previous != score) {
+			return int64(1)
+		}
+		return int64(0)
+	case "ZSCORE":
+		if score, ok := s.sets[args[0]][args[1]]; ok {
+			return strconv.FormatFloat(score, 'f', -1, 64)
+		}
+		return nil
+	case "ZREM":
+		if _, ok := s.sets[args[0]][args[1]]; !ok {
+			return int64(0)
+		}
+		delete(s.sets[args[0]], args[1])
+		s.versions[args[0]] += 1
+		return int64(1)
+	case "ZRANGEBYSCORE":
+		return s.rangeByScore(args)
+	}
+	return redisErrReply(fmt.Sprintf("ERR unknown command '%s'", command[0]))
+}
+
+// rangeByScore implements ZRANGEBYSCORE key min max [LIMIT offset count].
+func (s *redisStandIn) rangeByScore(args []string) any {
+	parseScore := func(value string) float64 {
+		switch value {
+		case "-inf":
+			return math.Inf(-1)
+		case "+inf":
+			return math.Inf(1)
+		}
+		score, _ := strconv.ParseFloat(value, 64)
+		return score
+	}
+	minScore, maxScore := parseScore(args[1]), parseScore(args[2])
+	var members []string
+	for member, score := range s.sets[args[0]] {
+		if score >= minScore && score <= maxScore {
+			members = append(members, member)
+		}
+	}
+	set := s.sets[args[0]]
+	sort.Slice(members, func(i, j int) bool {
+		if set[members[i]] != set[members[j]] {
+			return set[members[i]] < set[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	if len(args) == 6 && strings.ToUpper(args[3]) == "LIMIT" {
+		offset, _ := strconv.Atoi(args[4])
+		count, _ := strconv.Atoi(args[5])
+		if offset > len(members) {
+			offset = len(members)
+		}
+		members = members[offset:]
+		if count >= 0 && count < len(members) {
+			members = members[:count]
+		}
+	}
+	var reply = make([]any, 0, len(members))
+	for _, member := range members {
+		reply = append(reply, member)
+	}
+	return reply
+}
+
+func (s *redisStandIn) hash(key string) map[string]string {
+	if s.hashes[key] == nil {
+		s.hashes[key] = make(map[string]string)
+	}
+	return s.hashes[key]
+}
+
+func (s *redisStandIn) set(key string) map[string]float64 {
+	if s.sets[key] == nil {
+		s.sets[key] = make(map[string]float64)
+	}
+	return s.sets[key]
+}
+
+// redisTestUrl returns the url of the Redis server of the tests, the HOTALERT_TEST_REDIS_URL environment variable or
+// a new stand-in server.
+func redisTestUrl(t *testing.T) string {
+	if redisUrl := os.Getenv("HOTALERT_TEST_REDIS_URL"); redisUrl != "" {
+		return redisUrl
+	}
+	return "redis://:secret@" + newRedisStandIn(t).listener.Addr().String() + "/2"
+}
+
+// prefixedQueue prefixes the queue names of a Queue, so that the tests do not share keys on a real Redis server.
+type prefixedQueue struct {
+	Queue
+	prefix string
+}
+
+func (q prefixedQueue) Push(ctx context.Context, queue string, body []byte) error {
+	return q.Queue.Push(ctx, q.prefix+queue, body)
+}
+
+func (q prefixedQueue) Pop(ctx context.Context, queue string, lease time.Duration) (*Message, error) {
+	return q.Queue.Pop(ctx, q.prefix+queue, lease)
+}
+
+func (q prefixedQueue) Extend(ctx context.Context, queue string, id string, lease time.Duration) error {
+	return q.Queue.Extend(ctx, q.prefix+queue, id, lease)
+}
+
+func (q prefixedQueue) Ack(ctx context.Context, queue string, id string) error {
+	return q.Queue.Ack(ctx, q.prefix+queue, id)
+}
+
+func Test_RedisQueue(t *testing.T) {
+	testQueue(t, func(t *testing.T) Queue {
+		queue, err := Open(redisTestUrl(t))
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			_ = queue.Close()
+		})
+		prefix, err := newMessageId()
+		assert.NoError(t, err)
+		return prefixedQueue{Queue: queue, prefix: "hotalert-test-" + prefix + ":"}
+	})
+}
+
+func Test_DialRedis_Errors(t *testing.T) {
+	var tests = []struct {
+		TestName string
+		Url      string
+	}{
+		{"InvalidDatabase", "redis://127.0.0.1:6379/db"},
+		{"ConnectionRefused", "redis://127.0.0.1:1"},
+	}
+
+	for _, tv := range tests {
+		t.Run(tv.TestName, func(t *testing.T) {
+			redisUrl, err := url.Parse(tv.Url)
+			assert.NoError(t, err)
+			queue, err := DialRedis(redisUrl)
+			assert.Nil(t, queue)
+			assert.Error(t, err)
+		})
+	}
+}
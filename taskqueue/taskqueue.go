@@ -0,0 +1,67 @@
+package taskqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrUnknownMessage is returned when a message to extend or acknowledge is not in the queue.
+var ErrUnknownMessage = errors.New("unknown message")
+
+// ErrQueueClosed is returned by the operations of a closed queue.
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Message is a message popped from a Queue.
+type Message struct {
+	// Id identifies the message in its queue.
+	Id string
+	// Body is the content of the message.
+	Body []byte
+	// Deliveries is the number of times the message was popped, including this time.
+	Deliveries int
+}
+
+// Queue is a set of named message queues shared by producers and consumers.
+// A popped message is leased to its consumer, which acknowledges it once it is processed. If the lease expires first,
+// for example because the consumer crashed, the message is delivered again, possibly to another consumer.
+type Queue interface {
+	// Push adds a message to the named queue.
+	Push(ctx context.Context, queue string, body []byte) error
+	// Pop waits until a message of the named queue is available and leases it for the given duration.
+	// It returns the context error if the context is done first.
+	Pop(ctx context.Context, queue string, lease time.Duration) (*Message, error)
+	// Extend renews the lease of a popped message for the given duration from now.
+	Extend(ctx context.Context, queue string, id string, lease time.Duration) error
+	// Ack removes a popped message from the queue.
+	Ack(ctx context.Context, queue string, id string) error
+	// Close releases the resources of the queue.
+	Close() error
+}
+
+// Open returns the queue given by the url. The supported scheme is redis, as in redis://:password@localhost:6379/0.
+func Open(rawUrl string) (Queue, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	switch parsedUrl.Scheme {
+	case "redis":
+		return DialRedis(parsedUrl)
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported queue url scheme '%s', expected redis", parsedUrl.Scheme))
+}
+
+// newMessageId returns a new random message id. The ids start with the push time, so that the messages which can be
+// popped at the same time are sorted in push order.
+func newMessageId() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), hex.EncodeToString(bytes)), nil
+}
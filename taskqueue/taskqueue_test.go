@@ -0,0 +1,128 @@
+package taskqueue
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testQueue checks the behavior shared by all the Queue implementations.
+func testQueue(t *testing.T, newQueue func(t *testing.T) Queue) {
+	t.Run("PushPopAck", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		assert.NoError(t, queue.Push(ctx, "tasks", []byte("first")))
+		assert.NoError(t, queue.Push(ctx, "tasks", []byte("second")))
+
+		first, err := queue.Pop(ctx, "tasks", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(first.Body))
+		assert.Equal(t, 1, first.Deliveries)
+		second, err := queue.Pop(ctx, "tasks", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", string(second.Body))
+
+		assert.NoError(t, queue.Ack(ctx, "tasks", first.Id))
+		assert.ErrorIs(t, queue.Ack(ctx, "tasks", first.Id), ErrUnknownMessage)
+		assert.NoError(t, queue.Ack(ctx, "tasks", second.Id))
+	})
+
+	t.Run("LeaseExpires", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		assert.NoError(t, queue.Push(ctx, "tasks", []byte("task")))
+
+		// The message is delivered again once the lease of the first consumer expires.
+		leased, err := queue.Pop(ctx, "tasks", 100*time.Millisecond)
+		assert.NoError(t, err)
+		redelivered, err := queue.Pop(ctx, "tasks", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, leased.Id, redelivered.Id)
+		assert.Equal(t, "task", string(redelivered.Body))
+		assert.Equal(t, 2, redelivered.Deliveries)
+	})
+
+	t.Run("Extend", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		assert.NoError(t, queue.Push(ctx, "tasks", []byte("task")))
+
+		leased, err := queue.Pop(ctx, "tasks", 200*time.Millisecond)
+		assert.NoError(t, err)
+		assert.NoError(t, queue.Extend(ctx, "tasks", leased.Id, time.Minute))
+		timeoutCtx, cancel := context.WithTimeout(ctx, 400*time.Millisecond)
+		defer cancel()
+		_, err = queue.Pop(timeoutCtx, "tasks", time.Minute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		assert.ErrorIs(t, queue.Extend(ctx, "tasks", "unknown", time.Minute), ErrUnknownMessage)
+	})
+
+	t.Run("PopWaits", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = queue.Push(ctx, "tasks", []byte("task"))
+		}()
+		message, err := queue.Pop(ctx, "tasks", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "task", string(message.Body))
+	})
+
+	t.Run("SeparateQueues", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		assert.NoError(t, queue.Push(ctx, "results", []byte("result")))
+		timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		_, err := queue.Pop(timeoutCtx, "tasks", time.Minute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("ConcurrentConsumers", func(t *testing.T) {
+		queue := newQueue(t)
+		ctx := context.Background()
+		const messages = 20
+		for i := 0; i < messages; i++ {
+			assert.NoError(t, queue.Push(ctx, "tasks", []byte(fmt.Sprint(i))))
+		}
+
+		// Each message is delivered to a single consumer.
+		var mutex sync.Mutex
+		var delivered = make(map[string]int)
+		var consumers sync.WaitGroup
+		popCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		for i := 0; i < 4; i++ {
+			consumers.Add(1)
+			go func() {
+				defer consumers.Done()
+				for {
+					message, err := queue.Pop(popCtx, "tasks", time.Minute)
+					if err != nil {
+						return
+					}
+					mutex.Lock()
+					delivered[string(message.Body)] += 1
+					mutex.Unlock()
+					_ = queue.Ack(ctx, "tasks", message.Id)
+				}
+			}()
+		}
+		consumers.Wait()
+		assert.Len(t, delivered, messages)
+		for body, count := range delivered {
+			assert.Equal(t, 1, count, body)
+		}
+	})
+}
+
+func Test_Open_UnsupportedScheme(t *testing.T) {
+	queue, err := Open("nats://127.0.0.1:4222")
+	assert.Nil(t, queue)
+	assert.EqualError(t, err, "unsupported queue url scheme 'nats', expected redis")
+}
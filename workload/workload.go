@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"hotalert/alert"
+	"hotalert/httpclient"
 	"hotalert/logging"
 	"hotalert/task"
+	"hotalert/task/executor"
+	"net/http"
 	"time"
 )
 
@@ -14,6 +17,11 @@ import (
 type Workload struct {
 	tasksList  []*task.Task
 	alerterMap map[string]alert.Alerter
+	httpClient *http.Client
+	// httpOptions are the options of httpClient, nil when the workload has no http section.
+	httpOptions *httpclient.Options
+	// executorOptions are the options of the executor which runs the tasks.
+	executorOptions executor.Options
 }
 
 // NewWorkload returns a new Workload given the workload data.
@@ -21,6 +29,18 @@ func NewWorkload(workloadData map[string]any) (*Workload, error) {
 	var workload Workload
 	var err error
 
+	// The http client is optional and shared by the alerts and tasks.
+	err = workload.buildHttpClient(workloadData)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to build http contents: %s", err))
+	}
+
+	// The executor options are optional.
+	err = workload.buildExecutorOptions(workloadData)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to build executor contents: %s", err))
+	}
+
 	// Two important keys from here on: alert and tasks
 	err = workload.buildAlerterMap(workloadData)
 	if err != nil {
@@ -44,6 +64,58 @@ func (p *Workload) GetTasksLen() int {
 	return len(p.tasksList)
 }
 
+// SetName sets the name of the workload on its tasks, the executor takes tasks of the same priority in turns from
+// each workload.
+func (p *Workload) SetName(name string) {
+	for _, currentTask := range p.tasksList {
+		currentTask.Workload = name
+	}
+}
+
+// ExecutorOptions returns the options of the executor given in the workload, or the default options.
+func (p *Workload) ExecutorOptions() executor.Options {
+	return p.executorOptions
+}
+
+// buildExecutorOptions parses the optional executor section from the given workload data.
+// On failure, it returns an error.
+func (p *Workload) buildExecutorOptions(workloadData map[string]any) error {
+	p.executorOptions = executor.DefaultOptions()
+	executorContents, ok := workloadData["executor"]
+	if !ok {
+		return nil
+	}
+	executorContentsMap, ok := executorContents.(map[string]any)
+	if !ok {
+		return errors.New("key 'executor' is not a map type")
+	}
+
+	var err error
+	p.executorOptions, err = executor.OptionsFromMap(executorContentsMap)
+	return err
+}
+
+// buildHttpClient parses the optional http section from the given workload data and creates the shared http client.
+// On failure, it returns an error.
+func (p *Workload) buildHttpClient(workloadData map[string]any) error {
+	httpContents, ok := workloadData["http"]
+	if !ok {
+		return nil
+	}
+	httpContentsMap, ok := httpContents.(map[string]any)
+	if !ok {
+		return errors.New("key 'http' is not a map type")
+	}
+
+	options, err := httpclient.OptionsFromMap(httpContentsMap)
+	if err != nil {
+		return err
+	}
+	p.httpClient, err = httpclient.New(options)
+	p.httpOptions = &options
+	return err
+}
+
 // buildAlerterMap parses the alert section from the given workload data and creates alerter components.
 // On failure, it returns an error.
 func (p *Workload) buildAlerterMap(workloadData map[string]any) error {
@@ -72,6 +144,9 @@ func (p *Workload) buildAlerterMap(workloadData map[string]any) error {
 		if p.alerterMap[key] != nil {
 			return errors.New(fmt.Sprintf("alert section '%s' is a duplicate", key))
 		}
+		if setter, ok := alerter.(alert.HttpClientSetter); ok && p.httpClient != nil {
+			setter.SetHttpClient(p.httpClient)
+		}
 		p.alerterMap[key] = alerter
 	}
 
@@ -114,8 +189,14 @@ func (p *Workload) buildTasksArray(workloadData map[string]any) error {
 			continue
 		}
 
-		// Build task
+		// Build task and validate its options, so that invalid options are reported before the task runs.
 		tempTask := task.NewTask(executionFuncName, taskOptions, alert.DummyAlerter{})
+		tempTask.HttpClient = p.httpClient
+		tempTask.HttpOptions = p.httpOptions
+		if err := executor.ValidateTask(tempTask); err != nil {
+			logging.SugaredLogger.Errorf("error parsing entry %d in tasks array: %s", i, err)
+			continue
+		}
 
 		// Timeout (optional)
 		taskTimeout, ok := taskEntry["timeout"].(int)
@@ -123,6 +204,29 @@ func (p *Workload) buildTasksArray(workloadData map[string]any) error {
 			tempTask.Timeout = time.Duration(taskTimeout) * time.Second
 		}
 
+		// Priority (optional)
+		if value, ok := taskEntry["priority"]; ok {
+			tempTask.Priority, ok = value.(int)
+			if !ok {
+				logging.SugaredLogger.Errorf("error parsing entry %d in tasks array: invalid priority %v", i, value)
+				continue
+			}
+		}
+
+		// Id and dependencies (optional)
+		if err := buildDependencies(tempTask, taskEntry); err != nil {
+			logging.SugaredLogger.Errorf("error parsing entry %d in tasks array: %s", i, err)
+			continue
+		}
+
+		// Retry policy (optional)
+		retryPolicy, err := buildRetryPolicy(taskEntry)
+		if err != nil {
+			logging.SugaredLogger.Errorf("error parsing entry %d in tasks array: %s", i, err)
+			continue
+		}
+		tempTask.Retry = retryPolicy
+
 		// Alerter
 		taskAlerter, ok := taskEntry["alerter"].(string)
 		if ok {
@@ -143,10 +247,69 @@ func (p *Workload) buildTasksArray(workloadData map[string]any) error {
 	if len(p.tasksList) == 0 {
 		return errors.New("tasks list is empty or parsing has failed")
 	}
+	if err := executor.ValidateDependencies(p.tasksList); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// buildDependencies parses the optional id and depends_on keys of a task entry.
+func buildDependencies(currentTask *task.Task, taskEntry map[string]any) error {
+	if value, ok := taskEntry["id"]; ok {
+		currentTask.Id, ok = value.(string)
+		if !ok || currentTask.Id == "" {
+			return errors.New(fmt.Sprintf("invalid id %v", value))
+		}
+	}
+	if value, ok := taskEntry["depends_on"]; ok {
+		dependsOnArray, ok := value.([]any)
+		if !ok {
+			return errors.New(fmt.Sprintf("invalid depends_on %v", value))
+		}
+		for _, dependencyRaw := range dependsOnArray {
+			dependency, ok := dependencyRaw.(string)
+			if !ok {
+				return errors.New(fmt.Sprintf("invalid depends_on %v", dependencyRaw))
+			}
+			currentTask.DependsOn = append(currentTask.DependsOn, dependency)
+		}
+	}
+	return nil
+}
+
+// buildRetryPolicy parses the optional retries, retry_backoff and retry_on keys of a task entry.
+// The retry backoff is given in seconds, which may have a fraction.
+func buildRetryPolicy(taskEntry map[string]any) (task.RetryPolicy, error) {
+	var retryPolicy task.RetryPolicy
+	if value, ok := taskEntry["retries"]; ok {
+		retryPolicy.Retries, ok = value.(int)
+		if !ok {
+			return retryPolicy, errors.New(fmt.Sprintf("invalid retries %v", value))
+		}
+	}
+	if value, ok := taskEntry["retry_backoff"]; ok {
+		retryPolicy.Backoff, ok = executor.Seconds(value)
+		if !ok {
+			return retryPolicy, errors.New(fmt.Sprintf("invalid retry_backoff %v", value))
+		}
+	}
+	if value, ok := taskEntry["retry_on"]; ok {
+		retryOnArray, ok := value.([]any)
+		if !ok {
+			return retryPolicy, errors.New(fmt.Sprintf("invalid retry_on %v", value))
+		}
+		for _, retryOnRaw := range retryOnArray {
+			retryOn, ok := retryOnRaw.(string)
+			if !ok {
+				return retryPolicy, errors.New(fmt.Sprintf("invalid retry_on %v", retryOnRaw))
+			}
+			retryPolicy.RetryOn = append(retryPolicy.RetryOn, retryOn)
+		}
+	}
+	return retryPolicy, retryPolicy.Validate()
+}
+
 // FromYamlContent returns a new Workload given a yaml workload data definition.
 func FromYamlContent(contents []byte) (*Workload, error) {
 	var workloadData map[string]any
@@ -1,12 +1,22 @@
 package workload
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"hotalert/alert"
 	"hotalert/task"
+	"hotalert/task/executor"
 	"testing"
 	"time"
 )
 
+func init() {
+	// workload_test does not declare its options, so any options are accepted.
+	_ = executor.RegisterNewExecutionFunction("workload_test", func(ctx context.Context, task *task.Task) error {
+		return nil
+	})
+}
+
 func Test_FromYamlContent(t *testing.T) {
 	var fileContents = `
 tasks:
@@ -24,7 +34,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts:
   webhook_discord:
     webhook: https://webhook.url.com
@@ -80,7 +90,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts:
   webhook_discord:
     webhook: https://webhook.url.com
@@ -113,7 +123,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts:
   webhook_discord:
     webhook: https://webhook.url.com
@@ -142,7 +152,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alertx:
   webhook_discord:
     webhook: https://webhook.url.com
@@ -166,7 +176,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts: "I'm just a string please don't hurt me."
 `
 
@@ -186,7 +196,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts:
   webhook_discord:
     webhook: https://webhook.url.com
@@ -215,7 +225,7 @@ tasks:
       extra_bool: True
     timeout: 15
     alerter: "webhook_discord"
-    function: "web_scrape"
+    function: "workload_test"
 alerts:
   imcoolalerter:
     cool: true
@@ -378,3 +388,364 @@ func Test_FromYamlContent_InvalidExecutionFuncNameForTask(t *testing.T) {
 	assert.NotNil(t, currentWorkload)
 	assert.Len(t, currentWorkload.tasksList, 1)
 }
+
+var testTasksTaskHasInvalidOptions = `
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywrods: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: "Software Engineer, Backend"
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_InvalidOptionsForTask(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTasksTaskHasInvalidOptions))
+	assert.NoError(t, err)
+	assert.NotNil(t, currentWorkload)
+	assert.Len(t, currentWorkload.tasksList, 1)
+}
+
+var testHttpSection = `
+http:
+  proxy: socks5://127.0.0.1:1080
+  max_connections_per_host: 2
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_HttpSection(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testHttpSection))
+	assert.NoError(t, err)
+	assert.NotNil(t, currentWorkload.httpClient)
+
+	// The http client is shared by tasks and alerters.
+	assert.Same(t, currentWorkload.httpClient, currentWorkload.tasksList[0].HttpClient)
+	assert.Same(t, currentWorkload.httpOptions, currentWorkload.tasksList[0].HttpOptions)
+	discordAlerter, ok := currentWorkload.alerterMap["webhook_discord"].(*alert.DiscordWebhookAlerter)
+	assert.True(t, ok)
+	assert.Same(t, currentWorkload.httpClient, discordAlerter.HttpClient)
+}
+
+func Test_FromYamlContent_NoHttpSection(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTasksTaskHasInvalidAlerter2))
+	assert.NoError(t, err)
+	assert.Nil(t, currentWorkload.httpClient)
+	assert.Nil(t, currentWorkload.tasksList[0].HttpClient)
+	assert.Nil(t, currentWorkload.tasksList[0].HttpOptions)
+}
+
+var testHttpSectionInvalid = `
+http:
+  proxy: ftp://127.0.0.1
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+var testHttpSectionNotAMap = `
+http: "socks5://127.0.0.1:1080"
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_HttpSectionErrors(t *testing.T) {
+	for _, contents := range []string{testHttpSectionInvalid, testHttpSectionNotAMap} {
+		currentWorkload, err := FromYamlContent([]byte(contents))
+		assert.Nil(t, currentWorkload)
+		assert.Error(t, err)
+	}
+}
+
+var testExecutorSection = `
+executor:
+  workers: 2
+  queue_size: 500
+  full_queue: drop
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_ExecutorSection(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testExecutorSection))
+	assert.NoError(t, err)
+	assert.Equal(t, executor.Options{Workers: 2, QueueSize: 500, FullQueue: executor.FullQueueDrop}, currentWorkload.ExecutorOptions())
+}
+
+func Test_FromYamlContent_NoExecutorSection(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testHttpSection))
+	assert.NoError(t, err)
+	assert.Equal(t, executor.DefaultOptions(), currentWorkload.ExecutorOptions())
+}
+
+var testExecutorSectionInvalid = `
+executor:
+  workers: 0
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+var testExecutorSectionNotAMap = `
+executor: 5
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_ExecutorSectionErrors(t *testing.T) {
+	for _, contents := range []string{testExecutorSectionInvalid, testExecutorSectionNotAMap} {
+		currentWorkload, err := FromYamlContent([]byte(contents))
+		assert.Nil(t, currentWorkload)
+		assert.Error(t, err)
+	}
+}
+
+var testTaskRetryPolicy = `
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    timeout: 10
+    retries: 3
+    retry_backoff: 2
+    retry_on: ["timeout", "network"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    retries: 2
+    retry_backoff: 0.5
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    retries: 3
+    retry_on: ["4xx"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    retries: "3"
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_RetryPolicy(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTaskRetryPolicy))
+	assert.NoError(t, err)
+
+	// The tasks with an invalid retry policy are skipped.
+	assert.Equal(t, 2, currentWorkload.GetTasksLen())
+	assert.Equal(t, task.RetryPolicy{
+		Retries: 3,
+		Backoff: 2 * time.Second,
+		RetryOn: []string{task.RetryOnTimeout, task.RetryOnNetwork},
+	}, currentWorkload.GetTasks()[0].Retry)
+	// The backoff may have a fraction of a second.
+	assert.Equal(t, 500*time.Millisecond, currentWorkload.GetTasks()[1].Retry.Backoff)
+}
+
+var testTaskDependencies = `
+tasks:
+  - id: search
+    options:
+      command: "echo https://jobs.eu/search"
+    alerter: "webhook_discord"
+    function: "exec"
+  - id: page
+    depends_on: ["search"]
+    options:
+      url: "${tasks.search.stdout}"
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - id: 3
+    options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_Dependencies(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTaskDependencies))
+	assert.NoError(t, err)
+
+	// The tasks with an invalid id are skipped.
+	assert.Equal(t, 2, currentWorkload.GetTasksLen())
+	assert.Equal(t, "search", currentWorkload.GetTasks()[0].Id)
+	assert.Nil(t, currentWorkload.GetTasks()[0].DependsOn)
+	assert.Equal(t, "page", currentWorkload.GetTasks()[1].Id)
+	assert.Equal(t, []string{"search"}, currentWorkload.GetTasks()[1].DependsOn)
+}
+
+var testTaskDependenciesCycle = `
+tasks:
+  - id: search
+    depends_on: ["page"]
+    options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - id: page
+    depends_on: ["search"]
+    options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+var testTaskDependenciesUnknown = `
+tasks:
+  - id: page
+    depends_on: ["search"]
+    options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_DependenciesErrors(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTaskDependenciesCycle))
+	assert.Nil(t, currentWorkload)
+	assert.EqualError(t, err, "failed to build tasks contents: dependency cycle search -> page -> search")
+
+	currentWorkload, err = FromYamlContent([]byte(testTaskDependenciesUnknown))
+	assert.Nil(t, currentWorkload)
+	assert.EqualError(t, err, "failed to build tasks contents: task 'page' depends on unknown task 'search'")
+}
+
+var testTaskPriority = `
+tasks:
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    priority: 10
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    alerter: "webhook_discord"
+    function: "web_scrape"
+  - options:
+      url: https://jobs.eu
+      keywords: ["Software Engineer, Backend"]
+    priority: "high"
+    alerter: "webhook_discord"
+    function: "web_scrape"
+alerts:
+  webhook_discord:
+    webhook: https://webhook.url.com
+    message: "Hi, the keyword $keywords was found on page!"
+`
+
+func Test_FromYamlContent_Priority(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTaskPriority))
+	assert.NoError(t, err)
+
+	// The tasks with an invalid priority are skipped.
+	assert.Equal(t, 2, currentWorkload.GetTasksLen())
+	assert.Equal(t, 10, currentWorkload.GetTasks()[0].Priority)
+	assert.Equal(t, 0, currentWorkload.GetTasks()[1].Priority)
+}
+
+func Test_SetName(t *testing.T) {
+	currentWorkload, err := FromYamlContent([]byte(testTaskPriority))
+	assert.NoError(t, err)
+
+	currentWorkload.SetName("jobs.yaml")
+	for _, currentTask := range currentWorkload.GetTasks() {
+		assert.Equal(t, "jobs.yaml", currentTask.Workload)
+	}
+}